@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
-	"strconv"
 	"strings"
 	"time"
 
@@ -58,11 +57,14 @@ func (a API) registerAPIEndpoint(method string, path string, handle APIHandle, o
 		"method": method,
 		"path":   path,
 	})
-	a.server.router.Handle(method, path, a.apiPreHandle(handle, options))
+	a.server.router.HandleWithOptions(method, path, a.apiPreHandle(handle, options), options.routeOptions(RouteKindAPI))
 }
 
 func (a API) apiPreHandle(endpointHandle APIHandle, options HandleOptions) router.Handle {
 	return func(w http.ResponseWriter, request router.Request) {
+		w, finish := a.server.compress(w, request.HTTP, options)
+		defer finish()
+
 		if options.PreHandle != nil {
 			if err := options.PreHandle(w, request.HTTP); err != nil {
 				return
@@ -73,19 +75,15 @@ func (a API) apiPreHandle(endpointHandle APIHandle, options HandleOptions) route
 			return
 		}
 
-		if options.MaxBodyLength > 0 {
-			// We don't need to worry about this not being a number. Go's own HTTP server
-			// won't respond to requests like these
-			length, _ := strconv.ParseUint(request.HTTP.Header.Get("Content-Length"), 10, 64)
-
-			if length > options.MaxBodyLength {
-				log.PError("Rejecting API request with oversized body", map[string]interface{}{
-					"body_length": length,
-					"max_length":  options.MaxBodyLength,
-				})
-				w.WriteHeader(413)
-				return
+		if status := a.server.prepareBody(w, request.HTTP, options, "API"); status != 0 {
+			err := CommonErrors.PayloadTooLarge
+			if status == http.StatusUnsupportedMediaType {
+				err = CommonErrors.UnsupportedMediaType
 			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(JSONResponse{Error: err})
+			return
 		}
 
 		if options.AuthenticateMethod != nil {
@@ -99,7 +97,7 @@ func (a API) apiPreHandle(endpointHandle APIHandle, options HandleOptions) route
 					})
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusUnauthorized)
-					json.NewEncoder(w).Encode(Error{401, "Unauthorized"})
+					json.NewEncoder(w).Encode(Error{Code: 401, Message: "Unauthorized"})
 					return
 				}
 
@@ -120,9 +118,11 @@ func (a API) apiPostHandle(endpointHandle APIHandle, userData interface{}, optio
 		response := JSONResponse{}
 		request := Request{
 			HTTP:       r.HTTP,
-			Parameters: r.Parameters,
+			Parameters: routeParameters(r.Parameters),
 			UserData:   userData,
+			cleanup:    newRequestCleanup(),
 		}
+		defer request.cleanup.run()
 
 		start := time.Now()
 		defer func() {
@@ -139,6 +139,9 @@ func (a API) apiPostHandle(endpointHandle APIHandle, userData interface{}, optio
 		}()
 
 		data, resp, err := endpointHandle(request)
+		if bodyExceeded(r.HTTP) {
+			err = CommonErrors.PayloadTooLarge
+		}
 		if resp != nil {
 			for key, value := range resp.Headers {
 				w.Header().Set(key, value)
@@ -156,7 +159,7 @@ func (a API) apiPostHandle(endpointHandle APIHandle, userData interface{}, optio
 			response.Data = data
 		}
 		if !options.DontLogRequests {
-			log.PWrite(a.server.Options.RequestLogLevel, "API Request", map[string]interface{}{
+			log.PWrite(a.server.options().RequestLogLevel, "API Request", map[string]interface{}{
 				"remote_addr": RealRemoteAddr(r.HTTP),
 				"method":      r.HTTP.Method,
 				"url":         r.HTTP.URL,
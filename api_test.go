@@ -644,3 +644,57 @@ func TestAPIPreHandle(t *testing.T) {
 		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
 	}
 }
+
+func TestAPIHeadFallback(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	handle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return true, &web.APIResponse{Headers: map[string]string{"X-Example": "1"}}, nil
+	}
+	options := web.HandleOptions{}
+
+	path := randomString(5)
+	server.API.GET("/"+path, handle, options)
+
+	resp, err := http.Head(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+	// {"data":true}\n
+	if resp.ContentLength != 14 {
+		t.Fatalf("Unexpected content length. Expected %d got %d", 14, resp.ContentLength)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected content type '%s'", resp.Header.Get("Content-Type"))
+	}
+	if resp.Header.Get("X-Example") != "1" {
+		t.Fatalf("Missing header from API response")
+	}
+}
+
+func TestAPIParametersNotNil(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	handle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		// Handles may write to the parameters of a path without any
+		request.Parameters["example"] = "1"
+		return true, nil, nil
+	}
+	options := web.HandleOptions{}
+
+	path := randomString(5)
+	server.API.GET("/"+path, handle, options)
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+}
@@ -0,0 +1,249 @@
+package web
+
+import (
+	"encoding"
+	"fmt"
+	"mime"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindQuery will populate the struct v, which must be a pointer, from the URL query parameters of the request. Fields
+// are matched using the `query` tag, or the field name if there is no tag. Returns a validation error if any value could
+// not be parsed, with a message describing each invalid field. See FieldValidationError.
+//
+// Supported field types are strings, signed and unsigned integers, floats, booleans, [time.Time], [time.Duration], any
+// type that implements [encoding.TextUnmarshaler], slices and pointers of those types, and embedded structs. A slice
+// is populated from all values of a repeated parameter, other fields use the first value.
+//
+// Tag options:
+//
+//	Page    int       `query:"page" default:"1"`          // Value used if the parameter is not present
+//	Search  string    `query:"q,required"`                // Error if the parameter is not present
+//	Tags    []string  `query:"tag" default:"a,b"`         // Default values for slices are comma separated
+//	Since   time.Time `query:"since" layout:"2006-01-02"` // Times are RFC 3339 or a date by default
+//	Ignored string    `query:"-"`                         // Never populated
+//
+// Will panic if v is not a pointer to a struct or has a field of an unsupported type.
+func (r Request) BindQuery(v any) *Error {
+	values := url.Values{}
+	if r.HTTP != nil && r.HTTP.URL != nil {
+		values = r.HTTP.URL.Query()
+	}
+	return bindValues(v, "query", values, "Invalid query parameters")
+}
+
+// BindParameters will populate the struct v, which must be a pointer, from the URL path parameters of the request.
+// Fields are matched using the `param` tag, or the field name if there is no tag. See BindQuery for details.
+func (r Request) BindParameters(v any) *Error {
+	values := url.Values{}
+	for key, value := range r.Parameters {
+		values.Set(key, value)
+	}
+	return bindValues(v, "param", values, "Invalid parameters")
+}
+
+// BindForm will populate the struct v, which must be a pointer, from the form values in the body of the request, which
+// may be either application/x-www-form-urlencoded or multipart/form-data. Files in a multipart body are ignored, use
+// ParseMultipart or StreamMultipart to read files. Fields are matched using the `form` tag, or the field name if there
+// is no tag. See BindQuery for details.
+func (r Request) BindForm(v any) *Error {
+	mediaType, _, _ := mime.ParseMediaType(r.HTTP.Header.Get("Content-Type"))
+	var err error
+	if mediaType == "multipart/form-data" {
+		err = r.HTTP.ParseMultipartForm(1024 * 1024)
+	} else {
+		err = r.HTTP.ParseForm()
+	}
+	if err != nil {
+		if isBodyTooLarge(err) {
+			return CommonErrors.PayloadTooLarge
+		}
+		log.PError("Invalid form body", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ValidationError("Invalid form body")
+	}
+	return bindValues(v, "form", r.HTTP.PostForm, "Invalid form values")
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func bindValues(v any, tagName string, values url.Values, message string) *Error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("Bind target must be a pointer to a struct, got %T", v))
+	}
+
+	fields := []FieldError{}
+	bindStruct(target.Elem(), tagName, values, &fields)
+	if len(fields) == 0 {
+		return nil
+	}
+	return FieldValidationError(message, fields)
+}
+
+func bindStruct(v reflect.Value, tagName string, values url.Values, fields *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && !hasTag {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Pointer && embedded.Type().Elem().Kind() == reflect.Struct {
+				if !field.IsExported() {
+					continue
+				}
+				if embedded.IsNil() {
+					embedded.Set(reflect.New(embedded.Type().Elem()))
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && !isBindScalar(embedded.Type()) {
+				bindStruct(embedded, tagName, values, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		raw := values[name]
+		if len(raw) == 0 {
+			if defaultValue, ok := field.Tag.Lookup("default"); ok {
+				raw = []string{defaultValue}
+				if field.Type.Kind() == reflect.Slice {
+					raw = strings.Split(defaultValue, ",")
+				}
+			} else if options == "required" {
+				*fields = append(*fields, FieldError{Field: name, Message: "is required"})
+				continue
+			} else {
+				continue
+			}
+		}
+
+		if message := bindField(v.Field(i), raw, field.Tag.Get("layout")); message != "" {
+			*fields = append(*fields, FieldError{Field: name, Message: message})
+		}
+	}
+}
+
+// isBindScalar returns true if values of type t are parsed from a single string
+func isBindScalar(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// bindField sets v from raw, returning a message describing the problem if a value is not valid
+func bindField(v reflect.Value, raw []string, layout string) string {
+	switch {
+	case v.Kind() == reflect.Slice && !isBindScalar(v.Type()):
+		slice := reflect.MakeSlice(v.Type(), len(raw), len(raw))
+		for i, value := range raw {
+			if message := bindValue(slice.Index(i), value, layout); message != "" {
+				return message
+			}
+		}
+		v.Set(slice)
+		return ""
+	case v.Kind() == reflect.Pointer:
+		value := reflect.New(v.Type().Elem())
+		if message := bindField(value.Elem(), raw, layout); message != "" {
+			return message
+		}
+		v.Set(value)
+		return ""
+	}
+	return bindValue(v, raw[0], layout)
+}
+
+func bindValue(v reflect.Value, value string, layout string) string {
+	switch v.Type() {
+	case timeType:
+		if layout != "" {
+			t, err := time.Parse(layout, value)
+			if err != nil {
+				return "must be a time in the format " + layout
+			}
+			v.Set(reflect.ValueOf(t))
+			return ""
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return "must be a time in RFC 3339 format"
+		}
+		v.Set(reflect.ValueOf(t))
+		return ""
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "must be a duration"
+		}
+		v.SetInt(int64(d))
+		return ""
+	}
+
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return "is not valid"
+		}
+		return ""
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "must be a boolean"
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			if err.(*strconv.NumError).Err == strconv.ErrRange {
+				return "is out of range"
+			}
+			return "must be an integer"
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			if err.(*strconv.NumError).Err == strconv.ErrRange {
+				return "is out of range"
+			}
+			return "must be a positive integer"
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return "must be a number"
+		}
+		v.SetFloat(f)
+	default:
+		panic(fmt.Sprintf("Unsupported bind field type %s", v.Type()))
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package web_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web"
+)
+
+type bindPagination struct {
+	Page    int  `query:"page" form:"page" default:"1"`
+	PerPage uint `query:"per_page" form:"per_page" default:"25"`
+}
+
+type bindFilter struct {
+	bindPagination
+	Search   string        `query:"q,required" form:"q,required"`
+	Tags     []string      `query:"tag" form:"tag" default:"a,b"`
+	Active   *bool         `query:"active" form:"active"`
+	Ratio    float64       `query:"ratio" form:"ratio"`
+	Since    time.Time     `query:"since" form:"since"`
+	Day      time.Time     `query:"day" form:"day" layout:"02/01/2006"`
+	Timeout  time.Duration `query:"timeout" form:"timeout"`
+	Address  net.IP        `query:"address" form:"address"`
+	Small    int8          `query:"small" form:"small"`
+	Ignored  string        `query:"-" form:"-"`
+	Untagged string
+}
+
+func TestRequestBindQuery(t *testing.T) {
+	t.Parallel()
+
+	bind := func(query string) (bindFilter, *web.Error) {
+		filter := bindFilter{Ignored: "unchanged"}
+		req, rerr := http.NewRequest("GET", "/?"+query, nil)
+		if rerr != nil {
+			t.Fatalf("Error forming HTTP request: %s", rerr.Error())
+		}
+		request := web.MockRequest(web.MockRequestParameters{Request: req})
+		err := request.BindQuery(&filter)
+		return filter, err
+	}
+
+	filter, err := bind("q=widgets&tag=x&tag=y&active=true&ratio=0.5&since=2024-01-02T03:04:05Z&day=25/12/2024&timeout=5s&address=10.0.0.1&small=-5&Ignored=changed&Untagged=yes&page=3")
+	if err != nil {
+		t.Fatalf("Unexpected error binding query: %+v", err)
+	}
+	if filter.Search != "widgets" || strings.Join(filter.Tags, ",") != "x,y" || filter.Active == nil || !*filter.Active || filter.Ratio != 0.5 {
+		t.Errorf("Unexpected bound values: %+v", filter)
+	}
+	if !filter.Since.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) || filter.Day.Month() != time.December || filter.Timeout != 5*time.Second {
+		t.Errorf("Unexpected bound times: %+v", filter)
+	}
+	if filter.Address.String() != "10.0.0.1" || filter.Small != -5 || filter.Ignored != "unchanged" || filter.Untagged != "yes" {
+		t.Errorf("Unexpected bound values: %+v", filter)
+	}
+	if filter.Page != 3 || filter.PerPage != 25 {
+		t.Errorf("Unexpected embedded values: %+v", filter.bindPagination)
+	}
+
+	filter, err = bind("q=defaults&since=2024-06-01")
+	if err != nil {
+		t.Fatalf("Unexpected error binding query: %+v", err)
+	}
+	if filter.Page != 1 || strings.Join(filter.Tags, ",") != "a,b" || filter.Active != nil || filter.Since.Day() != 1 {
+		t.Errorf("Unexpected default values: %+v", filter)
+	}
+
+	_, err = bind("page=one&per_page=-1&active=maybe&small=500&since=yesterday&timeout=forever&address=nope")
+	if err == nil || err.Code != 400 || !strings.HasPrefix(err.Message, "Invalid query parameters: ") {
+		t.Fatalf("Unexpected error for invalid query: %+v", err)
+	}
+	for _, expected := range []string{
+		"page must be an integer",
+		"per_page must be a positive integer",
+		"q is required",
+		"active must be a boolean",
+		"small is out of range",
+		"since must be a time in RFC 3339 format",
+		"timeout must be a duration",
+		"address is not valid",
+	} {
+		if !strings.Contains(err.Message, expected) {
+			t.Errorf("Error missing '%s': %s", expected, err.Message)
+		}
+	}
+	if len(err.Fields) != 8 || err.Fields[0].Field != "page" || err.Fields[0].Message != "must be an integer" {
+		t.Errorf("Unexpected invalid fields: %+v", err.Fields)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("No panic seen when binding to a non-pointer")
+		}
+	}()
+	request := web.MockRequest(web.MockRequestParameters{})
+	request.BindQuery(bindFilter{})
+}
+
+func TestRequestBindParameters(t *testing.T) {
+	t.Parallel()
+
+	type widgetParams struct {
+		ID   uint64 `param:"id"`
+		Name string `param:"name" default:"unnamed"`
+	}
+
+	params := widgetParams{}
+	request := web.MockRequest(web.MockRequestParameters{Parameters: map[string]string{"id": "42"}})
+	if err := request.BindParameters(&params); err != nil || params.ID != 42 || params.Name != "unnamed" {
+		t.Errorf("Unexpected bound parameters: %+v %+v", params, err)
+	}
+
+	request = web.MockRequest(web.MockRequestParameters{Parameters: map[string]string{"id": "abc"}})
+	if err := request.BindParameters(&params); err == nil || err.Message != "Invalid parameters: id must be a positive integer" {
+		t.Errorf("Unexpected error for invalid parameter: %+v", err)
+	}
+}
+
+func TestRequestBindForm(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		filter := bindFilter{}
+		if err := request.BindForm(&filter); err != nil {
+			return nil, nil, err
+		}
+		return filter.Search + ":" + strings.Join(filter.Tags, ","), nil, nil
+	}, web.HandleOptions{MaxBodyLength: 1024})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("q", "multipart")
+	part, _ := writer.CreateFormFile("tag", "ignored.txt")
+	part.Write([]byte("file"))
+	writer.Close()
+
+	tests := []struct {
+		Description string
+		Body        *bytes.Buffer
+		ContentType string
+		Status      int
+		Data        string
+		Message     string
+	}{
+		{"urlencoded form", bytes.NewBufferString(url.Values{"q": {"form"}, "tag": {"x", "y"}}.Encode()), "application/x-www-form-urlencoded", 200, "form:x,y", ""},
+		{"multipart form", body, writer.FormDataContentType(), 200, "multipart:a,b", ""},
+		// Query parameters are not form values
+		{"missing form value", bytes.NewBufferString("page=2"), "application/x-www-form-urlencoded", 400, "", "Invalid form values: q is required"},
+		{"large form", bytes.NewBufferString(url.Values{"q": {strings.Repeat("a", 2048)}}.Encode()), "application/x-www-form-urlencoded", http.StatusRequestEntityTooLarge, "", ""},
+	}
+	for _, test := range tests {
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s?q=query", server.ListenPort, path), test.ContentType, test.Body)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		response := web.JSONResponse{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if resp.StatusCode != test.Status {
+			t.Errorf("Unexpected status for %s. Expected %d got %d", test.Description, test.Status, resp.StatusCode)
+		}
+		if test.Data != "" && response.Data != test.Data {
+			t.Errorf("Unexpected response for %s: %+v", test.Description, response)
+		}
+		if test.Message != "" && (response.Error == nil || response.Error.Message != test.Message || len(response.Error.Fields) != 1 || response.Error.Fields[0].Field != "q") {
+			t.Errorf("Unexpected error for %s: %+v", test.Description, response)
+		}
+	}
+}
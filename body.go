@@ -0,0 +1,93 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// limitedBody wraps a request body so that reads beyond the maximum body length fail with a *http.MaxBytesError
+type limitedBody struct {
+	reader   io.ReadCloser
+	Exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if isBodyTooLarge(err) {
+		b.Exceeded = true
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.reader.Close()
+}
+
+// isBodyTooLarge returns true if err is from reading a request body beyond the maximum body length
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return err != nil && errors.As(err, &maxBytesErr)
+}
+
+// bodyExceeded returns true if the handle tried to read beyond the maximum body length of r
+func bodyExceeded(r *http.Request) bool {
+	body, ok := r.Body.(*limitedBody)
+	return ok && body.Exceeded
+}
+
+// maxBodyLength returns the maximum body length for a handle, falling back to the server default
+func (s *Server) maxBodyLength(options HandleOptions) uint64 {
+	if options.MaxBodyLength > 0 {
+		return options.MaxBodyLength
+	}
+	return s.options().MaxBodyLength
+}
+
+// prepareBody will wrap the body of r so that reads beyond the maximum body length fail, regardless of the
+// Content-Length header or transfer encoding, and decompress the body if enabled. Returns 0 if the request may
+// continue, otherwise the status that the caller must respond with.
+//
+// Unless decompression is enabled, the maximum body length applies to the body as it was sent and compressed bodies are
+// passed to the handle unchanged. When decompression is enabled the decompressed length is limited as well, so that a
+// small compressed body can not expand to an unbounded size.
+func (s *Server) prepareBody(w http.ResponseWriter, r *http.Request, options HandleOptions, kind string) int {
+	limit := s.maxBodyLength(options)
+	if limit > 0 && r.ContentLength > 0 && uint64(r.ContentLength) > limit {
+		log.PError("Rejecting "+kind+" request with oversized body", map[string]interface{}{
+			"body_length": r.ContentLength,
+			"max_length":  limit,
+		})
+		return http.StatusRequestEntityTooLarge
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return 0
+	}
+
+	reader := r.Body
+	if limit > 0 {
+		reader = http.MaxBytesReader(w, reader, int64(limit))
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	decompression := s.options().Decompression
+	if decompression.Enabled && encoding != "" && encoding != "identity" {
+		decoder := decompression.decoder(encoding)
+		if decoder == nil {
+			log.PWarn("Rejecting "+kind+" request with unsupported content encoding", map[string]interface{}{
+				"content_encoding": encoding,
+			})
+			return http.StatusUnsupportedMediaType
+		}
+		reader = http.MaxBytesReader(w, &decodedBody{source: reader, decoder: decoder}, int64(decompression.maxLength(limit)))
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+	}
+
+	if reader != r.Body {
+		r.Body = &limitedBody{reader: reader}
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package web_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ecnepsnai/web"
+)
+
+var maxBodyLengthSmall = []byte(`{"foo":"bar"}`)
+var maxBodyLengthLarge = []byte(`{"foo":"` + strings.Repeat("a", 2048) + `"}`)
+
+func TestMaxBodyLengthAPI(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		v := map[string]string{}
+		if err := request.DecodeJSON(&v); err != nil {
+			return nil, nil, err
+		}
+		return v["foo"], nil, nil
+	}, web.HandleOptions{MaxBodyLength: 32})
+
+	// Bodies without a Content-Length are sent using chunked transfer encoding
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), "application/json", io.MultiReader(bytes.NewReader(maxBodyLengthSmall)))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected status for small body. Expected 200 got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), "application/json", io.MultiReader(bytes.NewReader(maxBodyLengthLarge)))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 413 {
+		t.Errorf("Unexpected status for large chunked body. Expected 413 got %d", resp.StatusCode)
+	}
+	response := web.JSONResponse{}
+	json.NewDecoder(resp.Body).Decode(&response)
+	if response.Error == nil || response.Error.Code != 413 {
+		t.Errorf("Unexpected API error for large body: %+v", response)
+	}
+}
+
+func TestMaxBodyLengthServer(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.MaxBodyLength = 1024
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		v := map[string]string{}
+		if err := request.DecodeJSON(&v); err != nil {
+			return nil, nil, err
+		}
+		return v["foo"], nil, nil
+	}, web.HandleOptions{})
+
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), "application/json", bytes.NewReader(maxBodyLengthLarge))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 413 {
+		t.Errorf("Unexpected status for body over server limit. Expected 413 got %d", resp.StatusCode)
+	}
+}
+
+func TestMaxBodyLengthHTTPEasy(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.HTTPEasy.POST("/"+path, func(request web.Request) web.HTTPResponse {
+		io.ReadAll(request.HTTP.Body)
+		return web.HTTPResponse{Reader: io.NopCloser(strings.NewReader("ok"))}
+	}, web.HandleOptions{MaxBodyLength: 32})
+
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), "application/json", io.MultiReader(bytes.NewReader(maxBodyLengthLarge)))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 413 {
+		t.Errorf("Unexpected status for large HTTPEasy body. Expected 413 got %d", resp.StatusCode)
+	}
+}
+
+func TestMaxBodyLengthHTTP(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	var readErr error
+	path := randomString(5)
+	server.HTTP.POST("/"+path, func(w http.ResponseWriter, request web.Request) {
+		_, readErr = io.ReadAll(request.HTTP.Body)
+		w.WriteHeader(200)
+	}, web.HandleOptions{MaxBodyLength: 32})
+
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), "application/json", io.MultiReader(bytes.NewReader(maxBodyLengthLarge)))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(readErr, &maxBytesErr) {
+		t.Errorf("Unexpected error reading large HTTP body: %v", readErr)
+	}
+}
+
+func TestMaxBodyLengthCompressed(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.MaxBodyLength = 1024
+
+	var body []byte
+	var encoding string
+	path := randomString(5)
+	server.HTTP.POST("/"+path, func(w http.ResponseWriter, request web.Request) {
+		encoding = request.HTTP.Header.Get("Content-Encoding")
+		body, _ = io.ReadAll(request.HTTP.Body)
+		w.WriteHeader(200)
+	}, web.HandleOptions{})
+
+	// Compressed bodies are passed to the handle unchanged unless decompression is enabled
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	writer.Write([]byte(`{"foo":"` + strings.Repeat("a", 256*1024) + `"}`))
+	writer.Close()
+	compressed := buf.Bytes()
+	if len(compressed) > 1024 {
+		t.Fatalf("Compressed body larger than expected: %d", len(compressed))
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected status for gzip body. Expected 200 got %d", resp.StatusCode)
+	}
+	if !bytes.Equal(body, compressed) || encoding != "gzip" {
+		t.Errorf("Compressed body was modified. Content-Encoding '%s'", encoding)
+	}
+}
@@ -2,12 +2,14 @@ package web
 
 // CommonErrors are common errors types suitable for API endpoints
 var CommonErrors = struct {
-	NotFound        *Error
-	BadRequest      *Error
-	Unauthorized    *Error
-	Forbidden       *Error
-	ServerError     *Error
-	TooManyRequests *Error
+	NotFound             *Error
+	BadRequest           *Error
+	Unauthorized         *Error
+	Forbidden            *Error
+	ServerError          *Error
+	TooManyRequests      *Error
+	PayloadTooLarge      *Error
+	UnsupportedMediaType *Error
 }{
 	NotFound: &Error{
 		Code:    404,
@@ -33,4 +35,12 @@ var CommonErrors = struct {
 		Code:    429,
 		Message: "Too Many Requests",
 	},
+	PayloadTooLarge: &Error{
+		Code:    413,
+		Message: "Payload Too Large",
+	},
+	UnsupportedMediaType: &Error{
+		Code:    415,
+		Message: "Unsupported Media Type",
+	},
 }
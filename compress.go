@@ -0,0 +1,305 @@
+package web
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+// CompressionEncoder describes a method that returns a writer that compresses data written to it into w using a
+// specific content coding. Closing the returned writer must flush any remaining data to w, but must not close w.
+type CompressionEncoder func(w io.Writer) (io.WriteCloser, error)
+
+var compressionEncoders = map[string]CompressionEncoder{
+	"gzip": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	"deflate": func(w io.Writer) (io.WriteCloser, error) {
+		// The "deflate" content coding is the zlib format (RFC 1950), not raw deflate
+		return zlib.NewWriter(w), nil
+	},
+}
+var compressionEncodersLock = &sync.RWMutex{}
+
+// RegisterCompressionEncoder will register an encoder for the content coding encoding, such as "br" or "zstd", which
+// can then be included in CompressionOptions.Encodings. The gzip and deflate codings are always available. Registering
+// an encoder for an existing coding replaces it.
+//
+// For example, using a third-party brotli package:
+//
+//	web.RegisterCompressionEncoder("br", func(w io.Writer) (io.WriteCloser, error) {
+//		return brotli.NewWriter(w), nil
+//	})
+func RegisterCompressionEncoder(encoding string, encoder CompressionEncoder) {
+	compressionEncodersLock.Lock()
+	defer compressionEncodersLock.Unlock()
+	compressionEncoders[strings.ToLower(encoding)] = encoder
+}
+
+func getCompressionEncoder(encoding string) CompressionEncoder {
+	compressionEncodersLock.RLock()
+	defer compressionEncodersLock.RUnlock()
+	return compressionEncoders[encoding]
+}
+
+// CompressionOptions describes options for compressing responses from API, HTTP, and HTTPEasy handles. Compression is
+// disabled by default.
+//
+// Responses are only compressed if the client accepts one of the encodings, the response is at least MinimumLength
+// bytes, and the content type is in MIMETypes. Responses that already have a 'Content-Encoding' or 'Content-Range'
+// header, partial content responses, responses without a body, server-sent event streams ('text/event-stream'), and
+// requests to upgrade the connection, such as websockets, are never compressed.
+type CompressionOptions struct {
+	// If responses should be compressed
+	Enabled bool
+	// The content codings that may be used, in order of preference. Codings other than "gzip" and "deflate" must be
+	// registered with RegisterCompressionEncoder. Defaults to "gzip" and "deflate".
+	Encodings []string
+	// The minimum length of a response body to be compressed. Defaults to 1024 bytes, set to a negative number to
+	// compress responses of any length.
+	MinimumLength int
+	// The content types that may be compressed. Entries ending with "/*" match any subtype, such as "text/*". Defaults
+	// to text, JSON, JavaScript, XML, and SVG.
+	MIMETypes []string
+}
+
+var defaultCompressionMIMETypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+func (o CompressionOptions) encodings() []string {
+	if len(o.Encodings) == 0 {
+		return []string{"gzip", "deflate"}
+	}
+	return o.Encodings
+}
+
+func (o CompressionOptions) minimumLength() int {
+	if o.MinimumLength == 0 {
+		return 1024
+	}
+	if o.MinimumLength < 0 {
+		return 0
+	}
+	return o.MinimumLength
+}
+
+// isCompressible returns true if the content type may be compressed
+func (o CompressionOptions) isCompressible(contentType string) bool {
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mimeType == "" || mimeType == "text/event-stream" {
+		return false
+	}
+
+	mimeTypes := o.MIMETypes
+	if len(mimeTypes) == 0 {
+		mimeTypes = defaultCompressionMIMETypes
+	}
+	for _, allowed := range mimeTypes {
+		allowed = strings.ToLower(allowed)
+		if strings.HasSuffix(allowed, "/*") {
+			if strings.HasPrefix(mimeType, allowed[0:len(allowed)-1]) {
+				return true
+			}
+		} else if mimeType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// compress returns a writer that will compress the response to the request if enabled, and a method that must be
+// called once the handle has returned. Responses to HEAD requests get the same headers as the equivalent GET request,
+// but without a body.
+func (s *Server) compress(w http.ResponseWriter, r *http.Request, options HandleOptions) (http.ResponseWriter, func()) {
+	compression := s.options().Compression
+	if !compression.Enabled || options.DisableCompression || r.Header.Get("Upgrade") != "" {
+		return w, func() {}
+	}
+
+	encoding := router.NegotiateContentEncoding(r.Header.Get("Accept-Encoding"), compression.encodings())
+	cw := &compressWriter{
+		ResponseWriter: w,
+		options:        compression,
+		encoding:       encoding,
+		head:           r.Method == "HEAD",
+	}
+	return cw, cw.finish
+}
+
+// compressWriter is a http.ResponseWriter that buffers the start of the response body until it can decide if the
+// response should be compressed
+type compressWriter struct {
+	http.ResponseWriter
+	options  CompressionOptions
+	encoding string
+	head     bool
+	status   int
+	buffer   []byte
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status != 0 {
+		return
+	}
+
+	cw.status = code
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		cw.buffer = append(cw.buffer, p...)
+		if len(cw.buffer) < cw.options.minimumLength() {
+			return len(p), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush will compress and send any buffered data to the client
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.bufferEligible())
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, bypassing compression
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the original response writer, for use by http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// bufferEligible returns true if enough data has been buffered to compress the response. Handles usually don't write
+// a body for HEAD requests, so the "Content-Length" header is used instead if there is one.
+func (cw *compressWriter) bufferEligible() bool {
+	if cw.head && len(cw.buffer) == 0 && cw.Header().Get("Content-Length") != "" {
+		return true
+	}
+	return len(cw.buffer) > 0 && len(cw.buffer) >= cw.options.minimumLength()
+}
+
+// decide will write the response headers and any buffered data, compressing the response if it is eligible
+func (cw *compressWriter) decide(eligible bool) error {
+	cw.decided = true
+	header := cw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buffer))
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" || cw.status == http.StatusPartialContent {
+		eligible = false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < cw.options.minimumLength() {
+		eligible = false
+	}
+
+	compressible := cw.options.isCompressible(header.Get("Content-Type"))
+	if compressible && header.Get("Content-Encoding") == "" {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if eligible && compressible && cw.encoding != "" {
+		if encoder := getCompressionEncoder(cw.encoding); encoder != nil {
+			// The body of a response to a HEAD request is discarded, it is only compressed to match the GET response
+			var target io.Writer = cw.ResponseWriter
+			if cw.head {
+				target = io.Discard
+			}
+			writer, err := encoder(target)
+			if err != nil {
+				log.PError("Error creating response compression encoder", map[string]interface{}{
+					"encoding": cw.encoding,
+					"error":    err.Error(),
+				})
+			} else {
+				cw.encoder = writer
+				header.Set("Content-Encoding", cw.encoding)
+				header.Del("Content-Length")
+				header.Del("Accept-Ranges")
+				// The compressed representation is not byte-for-byte identical to the original
+				if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+					header.Set("ETag", "W/"+etag)
+				}
+			}
+		}
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buffer := cw.buffer
+	cw.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buffer)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buffer)
+	return err
+}
+
+// finish will send any buffered data and complete the compressed response
+func (cw *compressWriter) finish() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buffer) == 0 {
+			// Nothing was written, let the server send its default response
+			cw.decided = true
+			return
+		}
+		cw.decide(cw.bufferEligible())
+	}
+	if cw.encoder != nil {
+		cw.encoder.Close()
+	}
+}
@@ -0,0 +1,272 @@
+package web_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ecnepsnai/web"
+)
+
+var compressionData = strings.Repeat("compress me ", 100)
+
+func TestCompressionAPI(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.Compression = web.CompressionOptions{
+		Enabled:       true,
+		MinimumLength: 64,
+	}
+
+	path := randomString(5)
+	server.API.GET("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return compressionData, nil, nil
+	}, web.HandleOptions{})
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" || resp.Header.Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Unexpected headers for compressed API response: %v", resp.Header)
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading gzip response: %s", err.Error())
+	}
+	body, _ := io.ReadAll(reader)
+	if !strings.Contains(string(body), compressionData) {
+		t.Errorf("Unexpected decompressed API response")
+	}
+}
+
+func TestCompressionHTTPEasy(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.Compression = web.CompressionOptions{
+		Enabled:       true,
+		MinimumLength: 64,
+	}
+
+	path := randomString(5)
+	server.HTTPEasy.GET("/"+path, func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{
+			Reader:      io.NopCloser(strings.NewReader(compressionData)),
+			ContentType: "text/plain",
+			ETag:        `"v1"`,
+		}
+	}, web.HandleOptions{})
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Accept-Encoding", "br, deflate")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "deflate" || resp.Header.Get("ETag") != `W/"v1"` {
+		t.Fatalf("Unexpected headers for compressed HTTPEasy response: %v", resp.Header)
+	}
+	reader, err := zlib.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading deflate response: %s", err.Error())
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != compressionData {
+		t.Errorf("Unexpected decompressed HTTPEasy response")
+	}
+}
+
+func TestCompressionSkipped(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.Compression = web.CompressionOptions{
+		Enabled:       true,
+		MinimumLength: 64,
+	}
+
+	server.API.GET("/small", func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return "small", nil, nil
+	}, web.HandleOptions{})
+	server.API.GET("/disabled", func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return compressionData, nil, nil
+	}, web.HandleOptions{DisableCompression: true})
+	server.HTTPEasy.GET("/binary", func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{
+			Reader:      io.NopCloser(strings.NewReader(compressionData)),
+			ContentType: "image/png",
+		}
+	}, web.HandleOptions{})
+	server.HTTP.GET("/encoded", func(w http.ResponseWriter, request web.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "identity")
+		w.Write([]byte(compressionData))
+	}, web.HandleOptions{})
+	server.HTTP.GET("/events", func(w http.ResponseWriter, request web.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: " + compressionData + "\n\n"))
+		w.(http.Flusher).Flush()
+	}, web.HandleOptions{})
+
+	for _, path := range []string{"/small", "/disabled", "/binary", "/encoded", "/events"} {
+		req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d%s", server.ListenPort, path), nil)
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.Header.Get("Content-Encoding") == "gzip" {
+			t.Errorf("Response for '%s' should not be compressed", path)
+		}
+	}
+}
+
+func TestCompressionNotAccepted(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.Compression = web.CompressionOptions{
+		Enabled:       true,
+		MinimumLength: 64,
+	}
+
+	path := randomString(5)
+	server.API.GET("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return compressionData, nil, nil
+	}, web.HandleOptions{})
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" || resp.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Unexpected headers for uncompressed API response: %v", resp.Header)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), compressionData) {
+		t.Errorf("Unexpected uncompressed API response")
+	}
+}
+
+func TestCompressionHEAD(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.Compression = web.CompressionOptions{
+		Enabled:       true,
+		MinimumLength: 64,
+	}
+
+	path := randomString(5)
+	server.HTTPEasy.GETHEAD("/"+path, func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{
+			Reader:        io.NopCloser(strings.NewReader(compressionData)),
+			ContentType:   "text/plain",
+			ContentLength: uint64(len(compressionData)),
+		}
+	}, web.HandleOptions{})
+	server.HTTPEasy.GETHEAD("/small/"+path, func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{
+			Reader:        io.NopCloser(strings.NewReader("small")),
+			ContentType:   "text/plain",
+			ContentLength: 5,
+		}
+	}, web.HandleOptions{})
+
+	do := func(method string, path string) *http.Response {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, p := range []string{path, "small/" + path} {
+		get := do("GET", p)
+		head := do("HEAD", p)
+		for _, header := range []string{"Content-Encoding", "Vary"} {
+			if get.Header.Get(header) != head.Header.Get(header) {
+				t.Errorf("Mismatched %s header for '%s'. GET '%s' HEAD '%s'", header, p, get.Header.Get(header), head.Header.Get(header))
+			}
+		}
+		// The length of a compressed body is unknown without the body, so it may be omitted from the HEAD response
+		if head.ContentLength != -1 && get.ContentLength != head.ContentLength {
+			t.Errorf("Mismatched content length for '%s'. GET %d HEAD %d", p, get.ContentLength, head.ContentLength)
+		}
+	}
+	if resp := do("HEAD", path); resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("Unexpected encoding for HEAD request '%s'", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+func TestCompressionRegisterEncoder(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	web.RegisterCompressionEncoder("x-test", func(w io.Writer) (io.WriteCloser, error) {
+		return nopWriteCloser{w}, nil
+	})
+	server.Options.Compression = web.CompressionOptions{
+		Enabled:       true,
+		Encodings:     []string{"x-test", "gzip"},
+		MinimumLength: -1,
+	}
+
+	path := randomString(5)
+	server.HTTPEasy.GET("/"+path, func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{
+			Reader:      io.NopCloser(strings.NewReader("hello")),
+			ContentType: "text/plain",
+		}
+	}, web.HandleOptions{})
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Accept-Encoding", "gzip, x-test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("Content-Encoding") != "x-test" || string(body) != "hello" {
+		t.Errorf("Unexpected response for registered encoder: %v '%s'", resp.Header, body)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package web
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DecompressionDecoder describes a method that returns a reader that decompresses data read from r using a specific
+// content coding. Closing the returned reader must not close r.
+type DecompressionDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var decompressionDecoders = map[string]DecompressionDecoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		// The "deflate" content coding is the zlib format (RFC 1950), not raw deflate
+		return zlib.NewReader(r)
+	},
+}
+var decompressionDecodersLock = &sync.RWMutex{}
+
+// RegisterDecompressionDecoder will register a decoder for the content coding encoding, such as "zstd", which can then
+// be included in DecompressionOptions.Encodings. The gzip and deflate codings are always available. Registering a
+// decoder for an existing coding replaces it.
+//
+// For example, using a third-party zstd package:
+//
+//	web.RegisterDecompressionDecoder("zstd", func(r io.Reader) (io.ReadCloser, error) {
+//		decoder, err := zstd.NewReader(r)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return decoder.IOReadCloser(), nil
+//	})
+func RegisterDecompressionDecoder(encoding string, decoder DecompressionDecoder) {
+	decompressionDecodersLock.Lock()
+	defer decompressionDecodersLock.Unlock()
+	decompressionDecoders[strings.ToLower(encoding)] = decoder
+}
+
+func getDecompressionDecoder(encoding string) DecompressionDecoder {
+	decompressionDecodersLock.RLock()
+	defer decompressionDecodersLock.RUnlock()
+	return decompressionDecoders[encoding]
+}
+
+// DecompressionOptions describes options for decompressing request bodies sent with a 'Content-Encoding' header before
+// they are read by API, HTTP, and HTTPEasy handles. Decompression is disabled by default.
+//
+// Requests with a content coding that is not in Encodings, or with more than one content coding, are rejected with a
+// "415 Unsupported Media Type" response. Requests where the decompressed body is longer than MaxLength are rejected
+// with a "413 Payload Too Large" response, the same as a body that is longer than the maximum body length.
+//
+// If decompression is enabled, Start and Handler will panic if any coding in Encodings does not have a registered
+// decoder.
+type DecompressionOptions struct {
+	// If request bodies should be decompressed
+	Enabled bool
+	// The content codings that are accepted. Codings other than "gzip" and "deflate" must be registered with
+	// RegisterDecompressionDecoder. Defaults to "gzip" and "deflate".
+	Encodings []string
+	// The maximum length of a decompressed request body. Defaults to the maximum body length of the handle, or 10 MiB
+	// if there is no maximum body length.
+	MaxLength uint64
+}
+
+func (o DecompressionOptions) encodings() []string {
+	if len(o.Encodings) == 0 {
+		return []string{"gzip", "deflate"}
+	}
+	return o.Encodings
+}
+
+// validate will panic if decompression is enabled and any encoding does not have a registered decoder
+func (o DecompressionOptions) validate() {
+	if !o.Enabled {
+		return
+	}
+	for _, encoding := range o.encodings() {
+		if getDecompressionDecoder(strings.ToLower(encoding)) == nil {
+			panic(fmt.Sprintf("Unsupported decompression encoding '%s'", encoding))
+		}
+	}
+}
+
+// decoder returns the decoder for encoding, or nil if it is not accepted
+func (o DecompressionOptions) decoder(encoding string) DecompressionDecoder {
+	for _, accepted := range o.encodings() {
+		if strings.EqualFold(accepted, encoding) {
+			return getDecompressionDecoder(encoding)
+		}
+	}
+	return nil
+}
+
+func (o DecompressionOptions) maxLength(bodyLimit uint64) uint64 {
+	if o.MaxLength > 0 {
+		return o.MaxLength
+	}
+	if bodyLimit > 0 {
+		return bodyLimit
+	}
+	return 10 * 1024 * 1024
+}
+
+// decodedBody decompresses a request body. The decoder is created on the first read so that an invalid body produces
+// an error for the handle rather than the server.
+type decodedBody struct {
+	source  io.ReadCloser
+	decoder DecompressionDecoder
+	reader  io.ReadCloser
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		reader, err := b.decoder(b.source)
+		if err != nil {
+			return 0, err
+		}
+		b.reader = reader
+	}
+	return b.reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if b.reader != nil {
+		b.reader.Close()
+	}
+	return b.source.Close()
+}
@@ -0,0 +1,175 @@
+package web_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ecnepsnai/web"
+)
+
+var decompressionBody = []byte(`{"foo":"bar"}`)
+
+func gzipData(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+func deflateData(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	writer := zlib.NewWriter(buf)
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestDecompression(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	// A stand-in for a third-party coding such as zstd
+	web.RegisterDecompressionDecoder("x-base64", func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+	})
+	server.Options.Decompression = web.DecompressionOptions{
+		Enabled:   true,
+		Encodings: []string{"gzip", "deflate", "x-base64"},
+		MaxLength: 1024,
+	}
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		v := map[string]string{}
+		if err := request.DecodeJSON(&v); err != nil {
+			return nil, nil, err
+		}
+		return v["foo"], nil, nil
+	}, web.HandleOptions{})
+
+	for encoding, data := range map[string][]byte{
+		"gzip":     gzipData(decompressionBody),
+		"deflate":  deflateData(decompressionBody),
+		"x-base64": []byte(base64.StdEncoding.EncodeToString(decompressionBody)),
+		"identity": decompressionBody,
+		"":         decompressionBody,
+	} {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 || !strings.Contains(string(body), `"data":"bar"`) {
+			t.Errorf("Unexpected response for '%s' body: %d %s", encoding, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestDecompressionRejected(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+	server.Options.Decompression = web.DecompressionOptions{
+		Enabled:   true,
+		Encodings: []string{"gzip", "deflate"},
+		MaxLength: 1024,
+	}
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		v := map[string]string{}
+		if err := request.DecodeJSON(&v); err != nil {
+			return nil, nil, err
+		}
+		return v["foo"], nil, nil
+	}, web.HandleOptions{})
+
+	tests := []struct {
+		Description string
+		Encoding    string
+		Body        []byte
+		Status      int
+	}{
+		{"unsupported encoding", "br", decompressionBody, 415},
+		{"multiple encodings", "gzip, gzip", gzipData(gzipData(decompressionBody)), 415},
+		{"decompressed body over limit", "gzip", gzipData([]byte(`{"foo":"` + strings.Repeat("a", 4096) + `"}`)), 413},
+		{"invalid gzip body", "gzip", []byte("not gzip"), 400},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), bytes.NewReader(test.Body))
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Content-Encoding", test.Encoding)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		response := web.JSONResponse{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if resp.StatusCode != test.Status || response.Error == nil || response.Error.Code != test.Status {
+			t.Errorf("Unexpected response for %s. Expected %d got %d", test.Description, test.Status, resp.StatusCode)
+		}
+	}
+}
+
+func TestDecompressionDisabled(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.HTTP.POST("/"+path, func(w http.ResponseWriter, request web.Request) {
+		io.Copy(w, request.HTTP.Body)
+	}, web.HandleOptions{})
+
+	// Bodies are passed through unchanged when decompression is disabled
+	compressed := deflateData(decompressionBody)
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Content-Encoding", "deflate")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(body, compressed) {
+		t.Errorf("Body changed with decompression disabled")
+	}
+}
+
+func TestDecompressionUnregisteredEncoding(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		recover()
+	}()
+
+	server := web.New("127.0.0.1:0")
+	server.Options.Decompression = web.DecompressionOptions{
+		Enabled:   true,
+		Encodings: []string{"gzip", "zstd"},
+	}
+	server.Handler()
+
+	t.Errorf("No panic seen when one expected for encoding without a registered decoder")
+}
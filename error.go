@@ -1,11 +1,24 @@
 package web
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Error describes an API error object
 type Error struct {
 	Code    int    `json:"code,omitempty"`
 	Message string `json:"message,omitempty"`
+	// The request values that were not valid, if any. See FieldValidationError.
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes a request value that was not valid
+type FieldError struct {
+	// The name of the request value, such as the query parameter name
+	Field string `json:"field"`
+	// A description of the problem, such as "must be an integer"
+	Message string `json:"message"`
 }
 
 // ValidationError convenience method to make a error object for validation errors
@@ -15,3 +28,20 @@ func ValidationError(format string, v ...interface{}) *Error {
 		Message: fmt.Sprintf(format, v...),
 	}
 }
+
+// FieldValidationError convenience method to make a error object for request values that were not valid. The fields
+// are included in the error, and the message of the error is message followed by a description of each field, for
+// example:
+//
+//	Invalid query parameters: page must be an integer, q is required
+func FieldValidationError(message string, fields []FieldError) *Error {
+	descriptions := make([]string, len(fields))
+	for i, field := range fields {
+		descriptions[i] = field.Field + " " + field.Message
+	}
+	return &Error{
+		Code:    400,
+		Message: message + ": " + strings.Join(descriptions, ", "),
+		Fields:  fields,
+	}
+}
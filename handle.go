@@ -3,6 +3,9 @@ package web
 import (
 	"net/http"
 	"reflect"
+	"strconv"
+
+	"github.com/ecnepsnai/web/router"
 )
 
 // APIHandle describes a method signature for handling an API request
@@ -40,10 +43,58 @@ type HandleOptions struct {
 	// If omitted, a default handle is used.
 	UnauthorizedMethod func(w http.ResponseWriter, request *http.Request)
 	// MaxBodyLength defines the maximum length accepted for any HTTP request body. Requests that exceed this limit will
-	// receive a "413 Payload Too Large" response. The default value of 0 will not reject requests with large bodies.
+	// receive a "413 Payload Too Large" response. The default value of 0 uses the MaxBodyLength of the server options.
+	//
+	// The limit applies to the bytes read from the body, so requests that use chunked transfer encoding or send more
+	// data than their Content-Length are also limited. Reading beyond the limit returns a *http.MaxBytesError. API and
+	// HTTPEasy handles respond with a 413 status if the limit was reached, HTTP handles must respond themselves.
+	//
+	// Unless decompression is enabled in the server options, the limit applies only to the compressed bytes of a body
+	// sent with a 'Content-Encoding' header. A handle that decompresses the body itself must limit how much it reads
+	// from the decompressed stream, as a small compressed body can expand to an unbounded size.
 	MaxBodyLength uint64
 	// DontLogRequests if true then requests to this handle are not logged
 	DontLogRequests bool
+	// DisableCompression if true then responses from this handle are never compressed, even if compression is enabled
+	// in the server options
+	DisableCompression bool
+	// Name is an optional name for the route, which can be used to build a URL for the route using Server.URLFor.
+	// Multiple methods for the same path may share a name, but registering a name for a different path will panic.
+	Name string
+}
+
+// Route kinds included in [router.RouteInfo] for routes registered by this package. Routes registered with
+// HTTPEasy.Static use [router.RouteKindStatic].
+const (
+	RouteKindAPI      = "API"
+	RouteKindHTTP     = "HTTP"
+	RouteKindHTTPEasy = "HTTPEasy"
+	RouteKindSocket   = "Socket"
+)
+
+func (o HandleOptions) routeOptions(kind string) router.RouteOptions {
+	summary := map[string]string{}
+	if o.AuthenticateMethod != nil {
+		summary["authenticated"] = "true"
+	}
+	if o.UnauthorizedMethod != nil {
+		summary["unauthorized_method"] = "true"
+	}
+	if o.PreHandle != nil {
+		summary["pre_handle"] = "true"
+	}
+	if o.MaxBodyLength > 0 {
+		summary["max_body_length"] = strconv.FormatUint(o.MaxBodyLength, 10)
+	}
+	if o.DontLogRequests {
+		summary["dont_log_requests"] = "true"
+	}
+
+	return router.RouteOptions{
+		Name:    o.Name,
+		Kind:    kind,
+		Summary: summary,
+	}
 }
 
 func isUserdataNil(userData interface{}) bool {
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
-	"strconv"
 	"time"
 
 	"github.com/ecnepsnai/web/router"
@@ -55,11 +54,14 @@ func (h HTTP) registerHTTPEndpoint(method string, path string, handle HTTPHandle
 		"method": method,
 		"path":   path,
 	})
-	h.server.router.Handle(method, path, h.httpPreHandle(handle, options))
+	h.server.router.HandleWithOptions(method, path, h.httpPreHandle(handle, options), options.routeOptions(RouteKindHTTP))
 }
 
 func (h HTTP) httpPreHandle(endpointHandle HTTPHandle, options HandleOptions) router.Handle {
 	return func(w http.ResponseWriter, request router.Request) {
+		w, finish := h.server.compress(w, request.HTTP, options)
+		defer finish()
+
 		if options.PreHandle != nil {
 			if err := options.PreHandle(w, request.HTTP); err != nil {
 				return
@@ -70,19 +72,9 @@ func (h HTTP) httpPreHandle(endpointHandle HTTPHandle, options HandleOptions) ro
 			return
 		}
 
-		if options.MaxBodyLength > 0 {
-			// We don't need to worry about this not being a number. Go's own HTTP server
-			// won't respond to requests like these
-			length, _ := strconv.ParseUint(request.HTTP.Header.Get("Content-Length"), 10, 64)
-
-			if length > options.MaxBodyLength {
-				log.PError("Rejecting HTTP request with oversized body", map[string]interface{}{
-					"body_length": length,
-					"max_length":  options.MaxBodyLength,
-				})
-				w.WriteHeader(413)
-				return
-			}
+		if status := h.server.prepareBody(w, request.HTTP, options, "HTTP"); status != 0 {
+			w.WriteHeader(status)
+			return
 		}
 
 		var userData interface{}
@@ -118,14 +110,17 @@ func (h HTTP) httpPreHandle(endpointHandle HTTPHandle, options HandleOptions) ro
 			}
 		}()
 
+		cleanup := newRequestCleanup()
+		defer cleanup.run()
 		endpointHandle(w, Request{
 			HTTP:       request.HTTP,
-			Parameters: request.Parameters,
+			Parameters: routeParameters(request.Parameters),
 			UserData:   userData,
+			cleanup:    cleanup,
 		})
 		elapsed := time.Since(start)
 		if !options.DontLogRequests {
-			log.PWrite(h.server.Options.RequestLogLevel, "HTTP Request", map[string]interface{}{
+			log.PWrite(h.server.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
 				"remote_addr": RealRemoteAddr(request.HTTP),
 				"method":      request.HTTP.Method,
 				"url":         request.HTTP.URL,
@@ -3,9 +3,9 @@ package web
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"runtime/debug"
-	"strconv"
 	"strings"
 	"time"
 
@@ -33,13 +33,17 @@ type HTTPEasy struct {
 //
 //	Request for '/static/image.jpg' would read file '/usr/share/www/image.jpg'
 //
-// Will panic if any handle is registered under path. Attempting to register a new handle under path after calling
-// Static will panic.
+// Other handles may be registered under path, and they will take priority over any files. Will panic if another
+// wildcard handle is registered at path.
 //
 // Caching will be enabled by default for all files served by this router. The mtime of the file will be used for the
 // Last-Modified date.
 //
 // By default, the server will use the file extension (if any) to determine the MIME type for the response.
+//
+// Request paths can never refer to a file outside of directory, and symbolic links are only followed if they resolve
+// to a file within directory. Files and directories whose name begins with a '.' are not served. See
+// [router.Server.ServeFiles] for details.
 func (h HTTPEasy) Static(path string, directory string) {
 	log.PDebug("Serving files from directory", map[string]interface{}{
 		"directory": directory,
@@ -48,6 +52,104 @@ func (h HTTPEasy) Static(path string, directory string) {
 	h.server.router.ServeFiles(directory, path)
 }
 
+// StaticWithOptions registers a GET and HEAD handle for all requests under path to serve any files matching the
+// directory, using the given options for caching, index files, directory listings, MIME types, headers, and hidden
+// files. See Static for details.
+//
+// For example:
+//
+//	options := router.DefaultStaticOptions()
+//	options.GenerateDirectoryListing = false
+//	options.Headers = map[string]string{"X-Content-Type-Options": "nosniff"}
+//	server.HTTPEasy.StaticWithOptions("/static/", "/usr/share/www/", options)
+//
+// For a single-page application, set FallbackFile so that unknown paths are served the application shell:
+//
+//	options := router.DefaultStaticOptions()
+//	options.FallbackFile = "index.html"
+//	server.HTTPEasy.StaticWithOptions("/app/", "/usr/share/www/app/", options)
+func (h HTTPEasy) StaticWithOptions(path string, directory string, options router.StaticOptions) {
+	log.PDebug("Serving files from directory", map[string]interface{}{
+		"directory": directory,
+		"path":      path,
+	})
+	h.server.router.ServeFilesWithOptions(directory, path, options)
+}
+
+// StaticFS registers a GET and HEAD handle for all requests under path to serve any files matching the filesystem
+// fsys, such as an embed.FS. Files are served exactly as they are by Static.
+//
+// For example:
+//
+//	//go:embed assets
+//	var assets embed.FS
+//
+//	sub, _ := fs.Sub(assets, "assets")
+//	server.HTTPEasy.StaticFS("/static/", sub)
+//
+// Files that do not have a modification time, such as those from an embed.FS, use the time that StaticFS was called
+// for the Last-Modified date.
+func (h HTTPEasy) StaticFS(path string, fsys fs.FS) {
+	log.PDebug("Serving files from filesystem", map[string]interface{}{
+		"filesystem": fmt.Sprintf("%T", fsys),
+		"path":       path,
+	})
+	h.server.router.ServeFS(fsys, path)
+}
+
+// StaticFSWithOptions registers a GET and HEAD handle for all requests under path to serve any files matching the
+// filesystem fsys, using the given options. See StaticFS and StaticWithOptions for details.
+func (h HTTPEasy) StaticFSWithOptions(path string, fsys fs.FS, options router.StaticOptions) {
+	log.PDebug("Serving files from filesystem", map[string]interface{}{
+		"filesystem": fmt.Sprintf("%T", fsys),
+		"path":       path,
+	})
+	h.server.router.ServeFSWithOptions(fsys, path, options)
+}
+
+// WebDAV mounts a WebDAV handler at path for the local directory, allowing the directory to be browsed and modified
+// with WebDAV clients such as the file managers of most operating systems. Request paths are resolved exactly as they
+// are for StaticWithOptions, using the options in davOptions.Static. See [router.Server.ServeWebDAV] for details.
+//
+// Requests pass through the same PreHandle, rate limit, body length, and authentication checks as any other handle.
+// WebDAV clients will only prompt for credentials if the response to an unauthenticated request includes a
+// 'WWW-Authenticate' header, so most servers will want to provide an UnauthorizedMethod that sets one.
+//
+// For example:
+//
+//	davOptions := router.WebDAVOptions{Static: router.DefaultStaticOptions(), ReadOnly: true}
+//	server.HTTPEasy.WebDAV("/dav/", "/srv/share/", davOptions, web.HandleOptions{
+//		AuthenticateMethod: authenticate,
+//		UnauthorizedMethod: func(w http.ResponseWriter, r *http.Request) {
+//			w.Header().Set("WWW-Authenticate", `Basic realm="share"`)
+//			w.WriteHeader(401)
+//		},
+//	})
+func (h HTTPEasy) WebDAV(path string, directory string, davOptions router.WebDAVOptions, options HandleOptions) {
+	log.PDebug("Serving WebDAV from directory", map[string]interface{}{
+		"directory": directory,
+		"path":      path,
+		"read_only": davOptions.ReadOnly,
+	})
+	handler := h.server.router.WebDAVHandler(directory, path, davOptions)
+	h.server.router.Mount(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := h.server.mountedPreHandle(w, r, options, "WebDAV"); !ok {
+			return
+		}
+
+		start := time.Now()
+		handler.ServeHTTP(w, r)
+		if !options.DontLogRequests {
+			log.PWrite(h.server.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
+				"remote_addr": RealRemoteAddr(r),
+				"method":      r.Method,
+				"url":         r.URL,
+				"elapsed":     time.Since(start).String(),
+			})
+		}
+	}))
+}
+
 // GET register a new HTTP GET request handle
 func (h HTTPEasy) GET(path string, handle HTTPEasyHandle, options HandleOptions) {
 	h.registerHTTPEasyEndpoint("GET", path, handle, options)
@@ -96,11 +198,14 @@ func (h HTTPEasy) registerHTTPEasyEndpoint(method string, path string, handle HT
 		"method": method,
 		"path":   path,
 	})
-	h.server.router.Handle(method, path, h.httpPreHandle(handle, options))
+	h.server.router.HandleWithOptions(method, path, h.httpPreHandle(handle, options), options.routeOptions(RouteKindHTTPEasy))
 }
 
 func (h HTTPEasy) httpPreHandle(endpointHandle HTTPEasyHandle, options HandleOptions) router.Handle {
 	return func(w http.ResponseWriter, request router.Request) {
+		w, finish := h.server.compress(w, request.HTTP, options)
+		defer finish()
+
 		if options.PreHandle != nil {
 			if err := options.PreHandle(w, request.HTTP); err != nil {
 				return
@@ -111,19 +216,9 @@ func (h HTTPEasy) httpPreHandle(endpointHandle HTTPEasyHandle, options HandleOpt
 			return
 		}
 
-		if options.MaxBodyLength > 0 {
-			// We don't need to worry about this not being a number. Go's own HTTP server
-			// won't respond to requests like these
-			length, _ := strconv.ParseUint(request.HTTP.Header.Get("Content-Length"), 10, 64)
-
-			if length > options.MaxBodyLength {
-				log.PError("Rejecting HTTP request with oversized body", map[string]interface{}{
-					"body_length": length,
-					"max_length":  options.MaxBodyLength,
-				})
-				w.WriteHeader(413)
-				return
-			}
+		if status := h.server.prepareBody(w, request.HTTP, options, "HTTP"); status != 0 {
+			w.WriteHeader(status)
+			return
 		}
 
 		if options.AuthenticateMethod != nil {
@@ -155,9 +250,11 @@ func (h HTTPEasy) httpPostHandle(endpointHandle HTTPEasyHandle, userData interfa
 	return func(w http.ResponseWriter, r router.Request) {
 		request := Request{
 			HTTP:       r.HTTP,
-			Parameters: r.Parameters,
+			Parameters: routeParameters(r.Parameters),
 			UserData:   userData,
+			cleanup:    newRequestCleanup(),
 		}
+		defer request.cleanup.run()
 		start := time.Now()
 		defer func() {
 			if p := recover(); p != nil {
@@ -174,26 +271,60 @@ func (h HTTPEasy) httpPostHandle(endpointHandle HTTPEasyHandle, userData interfa
 		response := endpointHandle(request)
 		elapsed := time.Since(start)
 
+		if bodyExceeded(r.HTTP) {
+			if response.Reader != nil {
+				response.Reader.Close()
+			}
+			response = HTTPResponse{Status: 413}
+		}
+
 		if response.Reader != nil {
 			defer response.Reader.Close()
 		}
 
+		// Conditional requests are only evaluated for successful responses
+		if (response.ETag != "" || !response.LastModified.IsZero()) && (response.Status == 0 || response.Status == 200) {
+			if status := router.CheckPreconditions(r.HTTP, response.ETag, response.LastModified); status != 0 {
+				if status == http.StatusNotModified {
+					h.setValidatorHeaders(w, response)
+					for k, v := range response.Headers {
+						w.Header().Set(k, v)
+					}
+				}
+				if !options.DontLogRequests {
+					log.PWrite(h.server.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
+						"remote_addr": RealRemoteAddr(r.HTTP),
+						"method":      r.HTTP.Method,
+						"url":         r.HTTP.URL,
+						"elapsed":     elapsed.String(),
+						"status":      status,
+					})
+				}
+				w.WriteHeader(status)
+				return
+			}
+		}
+
 		// Return a HTTP range response only if:
 		// 1. A range was actually requested by the client
 		// 2. The reader implemented Seek
 		// 3. The response was either default or 200
 		ranges := router.ParseRangeHeader(r.HTTP.Header.Get("range"))
 		_, canSeek := response.Reader.(io.ReadSeekCloser)
-		if len(ranges) > 0 && (response.Status == 0 || response.Status == 200) && !h.server.Options.IgnoreHTTPRangeRequests && canSeek {
+		if len(ranges) > 0 && (response.Status == 0 || response.Status == 200) && !h.server.options().IgnoreHTTPRangeRequests && canSeek {
 			router.ServeHTTPRange(router.ServeHTTPRangeOptions{
-				Headers:     response.Headers,
-				Ranges:      ranges,
-				Reader:      response.Reader.(io.ReadSeekCloser),
-				TotalLength: response.ContentLength,
-				MIMEType:    response.ContentType,
-				Writer:      w,
+				Headers:      response.Headers,
+				Cookies:      response.Cookies,
+				Ranges:       ranges,
+				Reader:       response.Reader.(io.ReadSeekCloser),
+				TotalLength:  response.ContentLength,
+				MIMEType:     response.ContentType,
+				Writer:       w,
+				ETag:         response.ETag,
+				LastModified: response.LastModified,
+				IfRange:      r.HTTP.Header.Get("If-Range"),
 			})
-			log.PWrite(h.server.Options.RequestLogLevel, "HTTP Request", map[string]interface{}{
+			log.PWrite(h.server.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
 				"remote_addr": RealRemoteAddr(r.HTTP),
 				"method":      r.HTTP.Method,
 				"url":         r.HTTP.URL,
@@ -203,7 +334,7 @@ func (h HTTPEasy) httpPostHandle(endpointHandle HTTPEasyHandle, userData interfa
 			})
 			return
 		}
-		if canSeek && !h.server.Options.IgnoreHTTPRangeRequests {
+		if canSeek && !h.server.options().IgnoreHTTPRangeRequests {
 			w.Header().Set("Accept-Ranges", "bytes")
 		}
 
@@ -217,6 +348,8 @@ func (h HTTPEasy) httpPostHandle(endpointHandle HTTPEasyHandle, userData interfa
 			w.Header().Set("Content-Length", fmt.Sprintf("%d", response.ContentLength))
 		}
 
+		h.setValidatorHeaders(w, response)
+
 		for k, v := range response.Headers {
 			w.Header().Set(k, v)
 		}
@@ -230,7 +363,7 @@ func (h HTTPEasy) httpPostHandle(endpointHandle HTTPEasyHandle, userData interfa
 			code = response.Status
 		}
 		if !options.DontLogRequests {
-			log.PWrite(h.server.Options.RequestLogLevel, "HTTP Request", map[string]interface{}{
+			log.PWrite(h.server.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
 				"remote_addr": RealRemoteAddr(r.HTTP),
 				"method":      r.HTTP.Method,
 				"url":         r.HTTP.URL,
@@ -257,3 +390,13 @@ func (h HTTPEasy) httpPostHandle(endpointHandle HTTPEasyHandle, userData interfa
 		}
 	}
 }
+
+// setValidatorHeaders will add the etag and last-modified headers from the response, if any
+func (h HTTPEasy) setValidatorHeaders(w http.ResponseWriter, response HTTPResponse) {
+	if response.ETag != "" {
+		w.Header().Set("ETag", response.ETag)
+	}
+	if !response.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", response.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
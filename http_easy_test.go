@@ -11,10 +11,13 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/ecnepsnai/web"
+	"github.com/ecnepsnai/web/router"
 )
 
 func TestHTTPEasyAddRoutes(t *testing.T) {
@@ -358,6 +361,74 @@ func TestHTTPEasyCookies(t *testing.T) {
 	}
 }
 
+func TestHTTPEasyConditional(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	handle := func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{
+			Reader:        nopSeekCloser{bytes.NewReader([]byte("abcdef"))},
+			ContentType:   "text/plain",
+			ContentLength: 6,
+			ETag:          `"v1"`,
+			LastModified:  lastModified,
+		}
+	}
+
+	path := randomString(5)
+	server.HTTPEasy.GETHEAD("/"+path, handle, web.HandleOptions{})
+	server.HTTPEasy.PUT("/"+path, handle, web.HandleOptions{})
+	url := fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path)
+
+	do := func(method string, headers map[string]string) (*http.Response, string) {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			panic(err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	resp, body := do("GET", nil)
+	if resp.StatusCode != 200 || body != "abcdef" || resp.Header.Get("ETag") != `"v1"` || resp.Header.Get("Last-Modified") != lastModified.Format(http.TimeFormat) {
+		t.Errorf("Unexpected response: %d '%s' %v", resp.StatusCode, body, resp.Header)
+	}
+
+	resp, body = do("GET", map[string]string{"If-None-Match": `"v1"`})
+	if resp.StatusCode != 304 || body != "" || resp.Header.Get("ETag") != `"v1"` {
+		t.Errorf("Unexpected response for If-None-Match: %d '%s'", resp.StatusCode, body)
+	}
+
+	resp, _ = do("GET", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)})
+	if resp.StatusCode != 304 {
+		t.Errorf("Unexpected status for If-Modified-Since. Expected 304 got %d", resp.StatusCode)
+	}
+
+	resp, _ = do("PUT", map[string]string{"If-Match": `"v0"`})
+	if resp.StatusCode != 412 {
+		t.Errorf("Unexpected status for If-Match. Expected 412 got %d", resp.StatusCode)
+	}
+
+	resp, body = do("GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`})
+	if resp.StatusCode != 206 || body != "ab" {
+		t.Errorf("Unexpected response for matching If-Range: %d '%s'", resp.StatusCode, body)
+	}
+
+	resp, body = do("GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v0"`})
+	if resp.StatusCode != 200 || body != "abcdef" {
+		t.Errorf("Unexpected response for mismatched If-Range: %d '%s'", resp.StatusCode, body)
+	}
+}
+
 func TestHTTPEasyServeFile(t *testing.T) {
 	t.Parallel()
 	server := newServer()
@@ -388,6 +459,119 @@ func TestHTTPEasyServeFile(t *testing.T) {
 	}
 }
 
+func TestHTTPEasyServeFS(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	data := randomString(5)
+	name := randomString(5) + ".html"
+	server.HTTPEasy.StaticFS("/fs/", fstest.MapFS{
+		name: {Data: []byte(data)},
+	})
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/fs/%s", server.ListenPort, name))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading response body: %s", err.Error())
+	}
+	if string(body) != data {
+		t.Errorf("Unexpected response body. Expected '%s' got '%s'", data, body)
+	}
+}
+
+func TestHTTPEasyStaticWithOptions(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(path.Join(tmp, "default.htm"), []byte("index"), 0644); err != nil {
+		t.Fatalf("Error making temporary file: %s", err.Error())
+	}
+
+	options := router.DefaultStaticOptions()
+	options.IndexFileNames = []string{"default.htm"}
+	options.Headers = map[string]string{"X-Frame-Options": "DENY"}
+	server.HTTPEasy.StaticWithOptions("/options/", tmp, options)
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/options/", server.ListenPort))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "index" {
+		t.Errorf("Unexpected response. Expected 200 'index' got %d '%s'", resp.StatusCode, body)
+	}
+	if resp.Header.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("Custom header not included in response")
+	}
+}
+
+func TestHTTPEasyWebDAV(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(path.Join(tmp, "readme.txt"), []byte("readme"), 0644); err != nil {
+		t.Fatalf("Error making temporary file: %s", err.Error())
+	}
+
+	davPath := randomString(5)
+	server.HTTPEasy.WebDAV("/"+davPath+"/", tmp, router.WebDAVOptions{Static: router.DefaultStaticOptions()}, web.HandleOptions{
+		AuthenticateMethod: func(request *http.Request) interface{} {
+			if _, password, ok := request.BasicAuth(); ok && password == "secret" {
+				return 1
+			}
+			return nil
+		},
+		UnauthorizedMethod: func(w http.ResponseWriter, request *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dav"`)
+			w.WriteHeader(401)
+		},
+		MaxBodyLength: 16,
+	})
+
+	do := func(method string, name string, body string, authenticated bool) (*http.Response, string) {
+		req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%d/%s/%s", server.ListenPort, davPath, name), strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		if authenticated {
+			req.SetBasicAuth("user", "secret")
+		}
+		req.Header.Set("Depth", "1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	resp, _ := do("PROPFIND", "", "", false)
+	if resp.StatusCode != 401 || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("Unexpected response for unauthenticated request: %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp, body := do("PROPFIND", "", "", true); resp.StatusCode != 207 || !strings.Contains(body, "readme.txt") {
+		t.Errorf("Unexpected response for authenticated PROPFIND: %d", resp.StatusCode)
+	}
+	if resp, _ := do("PUT", "new.txt", "hello", true); resp.StatusCode != 201 {
+		t.Errorf("Unexpected response for authenticated PUT: %d", resp.StatusCode)
+	}
+	if resp, _ := do("PUT", "large.txt", strings.Repeat("x", 32), true); resp.StatusCode != 413 {
+		t.Errorf("Unexpected response for oversized PUT: %d", resp.StatusCode)
+	}
+	if data, _ := os.ReadFile(path.Join(tmp, "new.txt")); string(data) != "hello" {
+		t.Errorf("Unexpected file content '%s'", data)
+	}
+}
+
 func TestHTTPEasyUnauthorizedMethod(t *testing.T) {
 	t.Parallel()
 	server := newServer()
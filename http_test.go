@@ -477,3 +477,29 @@ func TestHTTPPanic(t *testing.T) {
 		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 500, resp.StatusCode)
 	}
 }
+
+func TestHTTPHeadFallback(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	handle := func(w http.ResponseWriter, r web.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(200)
+		w.Write([]byte("Hello world"))
+	}
+	options := web.HandleOptions{}
+
+	path := randomString(5)
+	server.HTTP.GET("/"+path, handle, options)
+
+	resp, err := http.Head(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+	if resp.ContentLength != 11 {
+		t.Fatalf("Unexpected content length. Expected %d got %d", 11, resp.ContentLength)
+	}
+}
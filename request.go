@@ -4,16 +4,29 @@ import (
 	"encoding/json"
 	"net"
 	"net/http"
+	"strconv"
 )
 
 // Request describes an API request
 type Request struct {
 	// The original HTTP request
 	HTTP *http.Request
-	// URL path parameters (not query parameters). Keys do not include the ':' or '*'.
+	// URL path parameters (not query parameters). Keys do not include the ':' or '*'. Never nil, even for paths without
+	// parameters.
 	Parameters map[string]string
 	// User data provided from the result of the AuthenticateRequest method on the handle options
 	UserData any
+
+	cleanup *requestCleanup
+}
+
+// routeParameters returns the parameters matched by the router, which are nil for paths without parameters, as a
+// map that handles may write to
+func routeParameters(parameters map[string]string) map[string]string {
+	if parameters == nil {
+		return map[string]string{}
+	}
+	return parameters
 }
 
 // Decoder describes a generic interface that has a Decode function
@@ -30,9 +43,13 @@ func (r Request) DecodeJSON(v any) *Error {
 	return r.Decode(v, json.NewDecoder(r.HTTP.Body))
 }
 
-// Decode will unmarshal the request body to v using the given decoder
+// Decode will unmarshal the request body to v using the given decoder. Returns CommonErrors.PayloadTooLarge if the body
+// is larger than the maximum body length of the handle.
 func (r Request) Decode(v any, decoder Decoder) *Error {
-	if err := json.NewDecoder(r.HTTP.Body).Decode(v); err != nil {
+	if err := decoder.Decode(v); err != nil {
+		if isBodyTooLarge(err) {
+			return CommonErrors.PayloadTooLarge
+		}
 		log.PError("Invalid request", map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -50,3 +67,69 @@ func (r Request) Decode(v any, decoder Decoder) *Error {
 func (r Request) RealRemoteAddr() net.IP {
 	return RealRemoteAddr(r.HTTP)
 }
+
+// ParamInt will parse the value of the URL path parameter name as a signed integer. Returns a validation error if the
+// parameter is missing or is not a valid integer.
+//
+// Use a constrained parameter, such as `/widgets/:id<int>`, to have the router reject invalid values before the handle
+// is called.
+func (r Request) ParamInt(name string) (int, *Error) {
+	value, err := r.ParamInt64(name)
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(value)) != value {
+		return 0, ValidationError("Parameter %s is out of range", name)
+	}
+	return int(value), nil
+}
+
+// ParamInt64 will parse the value of the URL path parameter name as a signed 64-bit integer. Returns a validation error
+// if the parameter is missing or is not a valid integer.
+func (r Request) ParamInt64(name string) (int64, *Error) {
+	value, err := r.param(name)
+	if err != nil {
+		return 0, err
+	}
+	i, perr := strconv.ParseInt(value, 10, 64)
+	if perr != nil {
+		return 0, ValidationError("Parameter %s must be an integer", name)
+	}
+	return i, nil
+}
+
+// ParamUint64 will parse the value of the URL path parameter name as an unsigned 64-bit integer. Returns a validation
+// error if the parameter is missing or is not a valid unsigned integer.
+func (r Request) ParamUint64(name string) (uint64, *Error) {
+	value, err := r.param(name)
+	if err != nil {
+		return 0, err
+	}
+	i, perr := strconv.ParseUint(value, 10, 64)
+	if perr != nil {
+		return 0, ValidationError("Parameter %s must be a positive integer", name)
+	}
+	return i, nil
+}
+
+// ParamBool will parse the value of the URL path parameter name as a boolean. Accepts the same values as
+// [strconv.ParseBool]. Returns a validation error if the parameter is missing or is not a valid boolean.
+func (r Request) ParamBool(name string) (bool, *Error) {
+	value, err := r.param(name)
+	if err != nil {
+		return false, err
+	}
+	b, perr := strconv.ParseBool(value)
+	if perr != nil {
+		return false, ValidationError("Parameter %s must be a boolean", name)
+	}
+	return b, nil
+}
+
+func (r Request) param(name string) (string, *Error) {
+	value, present := r.Parameters[name]
+	if !present {
+		return "", ValidationError("Missing parameter %s", name)
+	}
+	return value, nil
+}
@@ -42,3 +42,39 @@ func ExampleRequest_RealRemoteAddr() {
 
 	server.Start()
 }
+
+func ExampleRequest_ParamInt() {
+	server := web.New("127.0.0.1:8080")
+
+	handle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		widgetID, err := request.ParamInt("id")
+		if err != nil {
+			return nil, nil, err
+		}
+		return widgetID, nil, nil
+	}
+	server.API.GET("/widgets/:id<int>", handle, web.HandleOptions{})
+
+	server.Start()
+}
+
+func ExampleRequest_BindQuery() {
+	server := web.New("127.0.0.1:8080")
+
+	type widgetFilter struct {
+		Search  string `query:"q"`
+		Page    int    `query:"page" default:"1"`
+		PerPage int    `query:"per_page" default:"25"`
+	}
+
+	handle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		filter := widgetFilter{}
+		if err := request.BindQuery(&filter); err != nil {
+			return nil, nil, err
+		}
+		return filter, nil, nil
+	}
+	server.API.GET("/widgets", handle, web.HandleOptions{})
+
+	server.Start()
+}
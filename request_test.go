@@ -1,8 +1,11 @@
 package web_test
 
 import (
+	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/ecnepsnai/web"
@@ -60,3 +63,87 @@ func TestRequestRealIP(t *testing.T) {
 		t.Fatalf("Network error: %s", err.Error())
 	}
 }
+
+func TestRequestParamInt(t *testing.T) {
+	t.Parallel()
+
+	request := web.MockRequest(web.MockRequestParameters{
+		Parameters: map[string]string{
+			"id":       "1234",
+			"negative": "-1",
+			"name":     "foo",
+			"enabled":  "true",
+		},
+	})
+
+	if v, err := request.ParamInt("id"); err != nil || v != 1234 {
+		t.Errorf("Unexpected value for int parameter: %d %v", v, err)
+	}
+	if v, err := request.ParamInt64("negative"); err != nil || v != -1 {
+		t.Errorf("Unexpected value for int64 parameter: %d %v", v, err)
+	}
+	if v, err := request.ParamUint64("id"); err != nil || v != 1234 {
+		t.Errorf("Unexpected value for uint64 parameter: %d %v", v, err)
+	}
+	if v, err := request.ParamBool("enabled"); err != nil || !v {
+		t.Errorf("Unexpected value for bool parameter: %v %v", v, err)
+	}
+
+	if _, err := request.ParamInt("name"); err == nil || err.Code != 400 {
+		t.Errorf("No error seen for invalid int parameter")
+	}
+	if _, err := request.ParamUint64("negative"); err == nil || err.Code != 400 {
+		t.Errorf("No error seen for invalid uint64 parameter")
+	}
+	if _, err := request.ParamBool("name"); err == nil || err.Code != 400 {
+		t.Errorf("No error seen for invalid bool parameter")
+	}
+	if _, err := request.ParamInt("missing"); err == nil || err.Code != 400 {
+		t.Errorf("No error seen for missing parameter")
+	}
+}
+
+func TestRequestConstrainedParameter(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	handle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		id, err := request.ParamInt("id")
+		return id, nil, err
+	}
+	path := randomString(5)
+	server.API.GET("/"+path+"/:id<int>", handle, web.HandleOptions{})
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/%s/12", server.ListenPort, path))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+
+	resp, err = http.Get(fmt.Sprintf("http://localhost:%d/%s/twelve", server.ListenPort, path))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 404 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 404, resp.StatusCode)
+	}
+}
+
+func TestRequestDecode(t *testing.T) {
+	t.Parallel()
+
+	request := web.MockRequest(web.MockRequestParameters{
+		Body: io.NopCloser(strings.NewReader(`<example><foo>bar</foo></example>`)),
+	})
+	example := struct {
+		Foo string `xml:"foo"`
+	}{}
+	if err := request.Decode(&example, xml.NewDecoder(request.HTTP.Body)); err != nil {
+		t.Fatalf("Unexpected error decoding request: %s", err.Message)
+	}
+	if example.Foo != "bar" {
+		t.Errorf("Unexpected decoded value '%s'", example.Foo)
+	}
+}
@@ -3,6 +3,7 @@ package web
 import (
 	"io"
 	"net/http"
+	"time"
 )
 
 // APIResponse describes additional response properties for API handles
@@ -37,4 +38,10 @@ type HTTPResponse struct {
 	ContentType string
 	// The length of the content. Will overwrite any 'content-length' header in Headers.
 	ContentLength uint64
+	// The entity tag of the response, including the quotes and any "W/" prefix, such as "\"v1\"". If set, the
+	// 'etag' header is included and the conditional headers of the request are evaluated against it.
+	ETag string
+	// The time the content was last modified. If set, the 'last-modified' header is included and the conditional headers
+	// of the request are evaluated against it.
+	LastModified time.Time
 }
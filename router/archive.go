@@ -0,0 +1,209 @@
+package router
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Formats for directory archives, used as the value of the "archive" query parameter
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+)
+
+// archiveEntry describes a file or directory to include in a directory archive
+type archiveEntry struct {
+	// The name of the file within the filesystem
+	Path string
+	// The name of the file within the archive
+	Name string
+	Info fs.FileInfo
+}
+
+// archiveEntries returns all files and directories within dir that are not hidden, and the total size of all files.
+// Symbolic links to directories are not followed.
+func archiveEntries(fsys fs.FS, dir string, options StaticOptions) ([]archiveEntry, int64, error) {
+	entries := []archiveEntry{}
+	var total int64
+
+	err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == dir {
+			return nil
+		}
+		if options.isHidden(d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			// Symbolic links not permitted by the policy are treated as if they do not exist
+			return nil
+		}
+		if info.IsDir() && !d.IsDir() {
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		archiveName := strings.TrimPrefix(name, dir+"/")
+		if dir == "." {
+			archiveName = name
+		}
+		if info.IsDir() {
+			archiveName += "/"
+		} else {
+			total += info.Size()
+		}
+		entries = append(entries, archiveEntry{Path: name, Name: archiveName, Info: info})
+		return nil
+	})
+	return entries, total, err
+}
+
+func (s *impl) serveArchive(fsys fs.FS, dir string, format string, options StaticOptions, w http.ResponseWriter, req *http.Request) {
+	if format != ArchiveFormatZip && format != ArchiveFormatTarGz {
+		s.log.PInfo("Unsupported directory archive format", map[string]interface{}{
+			"directory_path": dir,
+			"format":         format,
+		})
+		w.WriteHeader(400)
+		return
+	}
+
+	entries, total, err := archiveEntries(fsys, dir, options)
+	if err != nil {
+		s.log.PError("Error reading directory for archive", map[string]interface{}{
+			"directory_path": dir,
+			"error":          err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+	if options.MaxArchiveSize > 0 && total > options.MaxArchiveSize {
+		s.log.PWarn("Rejected directory archive larger than maximum size", map[string]interface{}{
+			"directory_path": dir,
+			"size":           total,
+			"max_size":       options.MaxArchiveSize,
+		})
+		w.WriteHeader(403)
+		return
+	}
+
+	name := path.Base(dir)
+	if dir == "." {
+		name = "archive"
+	}
+
+	s.log.PDebug("Serving directory archive", map[string]interface{}{
+		"directory_path": dir,
+		"format":         format,
+		"files":          len(entries),
+		"size":           total,
+	})
+
+	options.setHeaders(w)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", strings.ReplaceAll(name, "\"", ""), format))
+	if format == ArchiveFormatZip {
+		w.Header().Set("Content-Type", "application/zip")
+	} else {
+		w.Header().Set("Content-Type", "application/gzip")
+	}
+	w.WriteHeader(200)
+	if req.Method == "HEAD" {
+		return
+	}
+
+	if format == ArchiveFormatZip {
+		err = writeZipArchive(fsys, entries, w)
+	} else {
+		err = writeTarGzArchive(fsys, entries, w)
+	}
+	if err != nil {
+		s.log.PError("Error writing directory archive", map[string]interface{}{
+			"directory_path": dir,
+			"format":         format,
+			"error":          err.Error(),
+		})
+	}
+}
+
+func writeZipArchive(fsys fs.FS, entries []archiveEntry, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for _, entry := range entries {
+		header, err := zip.FileInfoHeader(entry.Info)
+		if err != nil {
+			return err
+		}
+		header.Name = entry.Name
+		if !entry.Info.IsDir() {
+			header.Method = zip.Deflate
+		}
+
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if entry.Info.IsDir() {
+			continue
+		}
+		if err := copyArchiveFile(fsys, entry, writer); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeTarGzArchive(fsys fs.FS, entries []archiveEntry, w io.Writer) error {
+	compressor := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressor)
+	for _, entry := range entries {
+		header, err := tar.FileInfoHeader(entry.Info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = entry.Name
+		header.Uname = ""
+		header.Gname = ""
+
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if entry.Info.IsDir() {
+			continue
+		}
+		if err := copyArchiveFile(fsys, entry, archive); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressor.Close()
+}
+
+// copyArchiveFile copies exactly the size of the file when it was listed, as the archive header has already been
+// written
+func copyArchiveFile(fsys fs.FS, entry archiveEntry, w io.Writer) error {
+	f, err := fsys.Open(entry.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyN(w, f, entry.Info.Size())
+	return err
+}
@@ -0,0 +1,165 @@
+package router_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+var archiveFiles = fstest.MapFS{
+	"photos/a.txt":          {Data: []byte("aaa")},
+	"photos/.secret":        {Data: []byte("secret")},
+	"photos/backup.bak":     {Data: []byte("backup")},
+	"photos/album/b.txt":    {Data: []byte("bb")},
+	"photos/.git/config":    {Data: []byte("config")},
+	"photos/index.html":     {Data: []byte("index")},
+	"large/big.bin":         {Data: bytes.Repeat([]byte("x"), 2048)},
+	"disabled/readme.txt":   {Data: []byte("readme")},
+	"photos/album/c/d.txt":  {Data: []byte("d")},
+	"photos/album/empty.md": {Data: []byte{}},
+}
+
+var archiveContents = "a.txt,album/,album/b.txt,album/c/,album/c/d.txt,album/empty.md,index.html"
+
+func TestRouterStaticArchiveZip(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.DirectoryArchives = true
+	options.MaxArchiveSize = 1024
+	options.HiddenFiles = []string{"*.bak"}
+	server.ServeFSWithOptions(archiveFiles, "/files/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, body := testDirectoryListingRequest("http://"+listenAddress+"/files/photos/?archive=zip", "")
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/zip" || resp.Header.Get("Content-Disposition") != `attachment; filename="photos.zip"` {
+		t.Fatalf("Unexpected response for zip archive: %d %v", resp.StatusCode, resp.Header)
+	}
+	zipReader, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("Error reading zip archive: %s", err.Error())
+	}
+	names := []string{}
+	for _, file := range zipReader.File {
+		names = append(names, file.Name)
+		if file.Name == "album/b.txt" {
+			f, _ := file.Open()
+			data, _ := io.ReadAll(f)
+			if string(data) != "bb" {
+				t.Errorf("Unexpected content in zip archive '%s'", data)
+			}
+		}
+	}
+	sort.Strings(names)
+	if actual := strings.Join(names, ","); actual != archiveContents {
+		t.Errorf("Unexpected zip archive contents. Expected '%s' got '%s'", archiveContents, actual)
+	}
+}
+
+func TestRouterStaticArchiveTarGz(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.DirectoryArchives = true
+	options.MaxArchiveSize = 1024
+	options.HiddenFiles = []string{"*.bak"}
+	server.ServeFSWithOptions(archiveFiles, "/files/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, body := testDirectoryListingRequest("http://"+listenAddress+"/files/photos/?archive=tar.gz", "")
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/gzip" {
+		t.Fatalf("Unexpected response for tar.gz archive: %d %v", resp.StatusCode, resp.Header)
+	}
+	gzipReader, err := gzip.NewReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error reading gzip stream: %s", err.Error())
+	}
+	tarReader := tar.NewReader(gzipReader)
+	names := []string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error reading tar archive: %s", err.Error())
+		}
+		names = append(names, header.Name)
+		if header.Name == "a.txt" {
+			data, _ := io.ReadAll(tarReader)
+			if string(data) != "aaa" {
+				t.Errorf("Unexpected content in tar archive '%s'", data)
+			}
+		}
+	}
+	sort.Strings(names)
+	if actual := strings.Join(names, ","); actual != archiveContents {
+		t.Errorf("Unexpected tar archive contents. Expected '%s' got '%s'", archiveContents, actual)
+	}
+}
+
+func TestRouterStaticArchiveRejected(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.DirectoryArchives = true
+	options.MaxArchiveSize = 1024
+	options.HiddenFiles = []string{"*.bak"}
+	server.ServeFSWithOptions(archiveFiles, "/files/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testURL(t, "GET", "http://"+listenAddress+"/files/?archive=zip", 403)
+	testURL(t, "GET", "http://"+listenAddress+"/files/photos/?archive=rar", 400)
+}
+
+func TestRouterStaticArchiveIgnored(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFS(archiveFiles, "/default/")
+	options := router.DefaultStaticOptions()
+	options.DirectoryArchives = true
+	options.MaxArchiveSize = 1024
+	options.HiddenFiles = []string{"*.bak"}
+	server.ServeFSWithOptions(archiveFiles, "/files/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	if resp, body := testDirectoryListingRequest("http://"+listenAddress+"/files/photos/a.txt?archive=zip", ""); resp.StatusCode != 200 || body != "aaa" {
+		t.Errorf("Archive parameter should be ignored for files: %d", resp.StatusCode)
+	}
+	if resp, body := testDirectoryListingRequest("http://"+listenAddress+"/default/photos/?archive=zip", ""); resp.StatusCode != 200 || body != "index" {
+		t.Errorf("Archives should be disabled by default: %d", resp.StatusCode)
+	}
+}
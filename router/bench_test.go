@@ -0,0 +1,311 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type benchmarkResponseWriter struct {
+	header http.Header
+}
+
+func (w *benchmarkResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *benchmarkResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *benchmarkResponseWriter) WriteHeader(statusCode int) {}
+
+var benchmarkRoutes = []string{
+	"/",
+	"/about",
+	"/contact/",
+	"/users/",
+	"/users/all",
+	"/users/all/settings",
+	"/users/:username",
+	"/users/:username/posts",
+	"/users/:username/posts/:post_id<int>",
+	"/users/:username/followers",
+	"/orgs/:org/repos",
+	"/orgs/:org/members/:member",
+	"/repos/:owner/:repo",
+	"/repos/:owner/:repo/issues",
+	"/repos/:owner/:repo/issues/:number<int>",
+	"/repos/:owner/:repo/pulls",
+	"/static/*path",
+	"/api/v1/status",
+	"/api/v1/widgets",
+	"/api/v1/widgets/:id<int>",
+	"/api/v1/widgets/:id<int>/parts",
+	"/api/v2/status",
+	"/api/v2/widgets",
+}
+
+func newBenchmarkServer() *Server {
+	s := New()
+	s.impl.NotFoundHandle = func(http.ResponseWriter, *http.Request) {}
+	s.impl.MethodNotAllowedHandle = func(http.ResponseWriter, *http.Request) {}
+	for _, route := range benchmarkRoutes {
+		s.Handle("GET", route, func(http.ResponseWriter, Request) {})
+	}
+	return s
+}
+
+func benchmarkRequest(b *testing.B, s *Server, method, path string) {
+	req, err := http.NewRequest(method, "http://localhost"+path, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := &benchmarkResponseWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.impl.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkRouterStaticRoot(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/")
+}
+
+func BenchmarkRouterStatic(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/api/v1/status")
+}
+
+func BenchmarkRouterStaticDeep(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/users/all/settings")
+}
+
+func BenchmarkRouterParameter(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/users/ian")
+}
+
+func BenchmarkRouterParameterMultiple(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/repos/ecnepsnai/web/issues/26")
+}
+
+func BenchmarkRouterParameterBacktrack(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/users/all/posts")
+}
+
+func BenchmarkRouterWildcard(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/static/js/app/main.js")
+}
+
+func BenchmarkRouterNotFound(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "GET", "/api/v3/status")
+}
+
+func BenchmarkRouterMethodNotAllowed(b *testing.B) {
+	benchmarkRequest(b, newBenchmarkServer(), "POST", "/api/v1/status")
+}
+
+// mapRouter is the segment map router that preceded the radix tree, kept only so that the two can be compared. It does
+// not support constraints, host routes or static segments alongside parameters.
+type mapRouter struct {
+	lock  sync.RWMutex
+	index *mapEndpoint
+}
+
+type mapEndpoint struct {
+	Methods   map[string]Handle
+	Children  map[string]mapEndpoint
+	Parameter string
+}
+
+const (
+	mapKeyIndex     = "__router_index"
+	mapKeyParameter = "__router_parameter"
+	mapKeyWildcard  = "__router_wildcard"
+)
+
+func newMapEndpoint() mapEndpoint {
+	return mapEndpoint{
+		Methods:  map[string]Handle{},
+		Children: map[string]mapEndpoint{},
+	}
+}
+
+func newMapRouter(routes []string) *mapRouter {
+	index := newMapEndpoint()
+	r := &mapRouter{index: &index}
+	for _, route := range routes {
+		r.handle("GET", route, func(http.ResponseWriter, Request) {})
+	}
+	return r
+}
+
+func (r *mapRouter) handle(method, path string, handler Handle) {
+	if path[len(path)-1] == '/' {
+		path += mapKeyIndex
+	}
+	segments := strings.Split(path[1:], "/")
+
+	parent := r.index
+	for i, segment := range segments {
+		parameter := ""
+		if len(segment) > 1 {
+			if segment[0] == '*' {
+				parameter = segment[1:]
+				segment = mapKeyWildcard
+				i = len(segments) - 1
+			} else if segment[0] == ':' {
+				parameter = segment[1:]
+				segment = mapKeyParameter
+			}
+		}
+
+		child, exists := parent.Children[segment]
+		if !exists {
+			child = newMapEndpoint()
+			child.Parameter = parameter
+			parent.Children[segment] = child
+		}
+		parent = &child
+
+		if i == len(segments)-1 {
+			parent.Methods[method] = handler
+			return
+		}
+	}
+}
+
+func (r *mapRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.RLock()
+	defer func() {
+		r.lock.RUnlock()
+		if recover() != nil {
+			w.WriteHeader(500)
+		}
+	}()
+
+	parameters := map[string]string{}
+
+	path := req.URL.Path
+	if path[len(path)-1] == '/' {
+		path += mapKeyIndex
+	}
+	segments := strings.Split(path[1:], "/")
+
+	parent := r.index
+	for i, segment := range segments {
+		child, exists := parent.Children[segment]
+		if !exists {
+			if wildcardChild, exists := parent.Children[mapKeyWildcard]; exists {
+				handler, present := wildcardChild.Methods[req.Method]
+				if !present {
+					w.WriteHeader(405)
+					return
+				}
+				parameters[wildcardChild.Parameter] = strings.Join(segments[i:], "/")
+				handler(w, Request{req, parameters})
+				return
+			}
+			parameterChild, exists := parent.Children[mapKeyParameter]
+			if !exists {
+				w.WriteHeader(404)
+				return
+			}
+			child = parameterChild
+			parameters[parameterChild.Parameter] = segment
+		}
+		parent = &child
+
+		if i == len(segments)-1 {
+			handler, present := parent.Methods[req.Method]
+			if !present {
+				if len(parent.Methods) > 0 {
+					w.WriteHeader(405)
+					return
+				}
+				w.WriteHeader(404)
+				return
+			}
+			handler(w, Request{req, parameters})
+			return
+		}
+	}
+	w.WriteHeader(404)
+}
+
+// compareRoutes are the benchmark routes that the segment map router supports
+var compareRoutes = []string{
+	"/",
+	"/about",
+	"/contact/",
+	"/users/",
+	"/users/:username",
+	"/users/:username/posts",
+	"/users/:username/posts/:post_id",
+	"/users/:username/followers",
+	"/orgs/:org/repos",
+	"/orgs/:org/members/:member",
+	"/repos/:owner/:repo",
+	"/repos/:owner/:repo/issues",
+	"/repos/:owner/:repo/issues/:number",
+	"/repos/:owner/:repo/pulls",
+	"/static/*path",
+	"/api/v1/status",
+	"/api/v1/widgets",
+	"/api/v1/widgets/:id",
+	"/api/v1/widgets/:id/parts",
+	"/api/v2/status",
+	"/api/v2/widgets",
+}
+
+// BenchmarkRouterCompare measures the radix tree against the segment map router it replaced using the same routes.
+// Compare the "tree" and "map" results of each request.
+func BenchmarkRouterCompare(b *testing.B) {
+	tree := New()
+	tree.impl.NotFoundHandle = func(http.ResponseWriter, *http.Request) {}
+	tree.impl.MethodNotAllowedHandle = func(http.ResponseWriter, *http.Request) {}
+	for _, route := range compareRoutes {
+		tree.Handle("GET", route, func(http.ResponseWriter, Request) {})
+	}
+	routers := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"tree", tree.impl},
+		{"map", newMapRouter(compareRoutes)},
+	}
+
+	requests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"StaticRoot", "GET", "/"},
+		{"Static", "GET", "/api/v1/status"},
+		{"Parameter", "GET", "/users/ian"},
+		{"ParameterMultiple", "GET", "/repos/ecnepsnai/web/issues/26"},
+		{"Wildcard", "GET", "/static/js/app/main.js"},
+		{"NotFound", "GET", "/api/v3/status"},
+		{"MethodNotAllowed", "POST", "/api/v1/status"},
+	}
+
+	for _, request := range requests {
+		for _, router := range routers {
+			b.Run(request.name+"/"+router.name, func(b *testing.B) {
+				req, err := http.NewRequest(request.method, "http://localhost"+request.path, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				w := &benchmarkResponseWriter{header: http.Header{}}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					router.handler.ServeHTTP(w, req)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagMode describes how entity tags are generated for static files
+type ETagMode int
+
+const (
+	// ETagWeak generates a weak entity tag from the size and modification time of the file. Weak entity tags are cheap
+	// to generate, but can not be used with If-Range.
+	ETagWeak ETagMode = iota
+	// ETagStrong generates a strong entity tag from a hash of the contents of the file. The hash is calculated the first
+	// time a file is requested and again whenever the size or modification time of the file changes. Files that can not
+	// seek use a weak entity tag.
+	ETagStrong
+	// ETagDisabled does not include an entity tag for static files
+	ETagDisabled
+)
+
+// CheckPreconditions evaluates the conditional headers of the request (If-Match, If-Unmodified-Since, If-None-Match,
+// and If-Modified-Since) against the current entity tag and modification time of the resource, following the order of
+// precedence in RFC 9110. Either etag or lastModified may be empty if the resource does not have one.
+//
+// Returns 0 if the request should continue normally, 304 (Not Modified) if the client already has the current
+// representation, or 412 (Precondition Failed) if a precondition was not met. Handles that return 304 should include
+// the ETag and Last-Modified headers but no body, handles that return 412 should not perform the request.
+func CheckPreconditions(req *http.Request, etag string, lastModified time.Time) int {
+	lastModified = lastModified.Truncate(time.Second)
+
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		if !etagListMatches(ifMatch, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ifUnmodifiedSince := req.Header.Get("If-Unmodified-Since"); ifUnmodifiedSince != "" && !lastModified.IsZero() {
+		if since, err := http.ParseTime(ifUnmodifiedSince); err == nil && lastModified.After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	isRead := req.Method == "GET" || req.Method == "HEAD"
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etagListMatches(ifNoneMatch, etag, false) {
+			if isRead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ifModifiedSince := req.Header.Get("If-Modified-Since"); ifModifiedSince != "" && isRead && !lastModified.IsZero() {
+		if since, err := http.ParseTime(ifModifiedSince); err == nil && !lastModified.After(since) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// CheckIfRange returns true if a range request should be served as a range, or false if the If-Range header of the
+// request does not match the current entity tag or modification time of the resource and the complete representation
+// should be served instead. Returns true if the request does not have an If-Range header.
+func CheckIfRange(req *http.Request, etag string, lastModified time.Time) bool {
+	return ifRangeMatches(req.Header.Get("If-Range"), etag, lastModified)
+}
+
+func ifRangeMatches(ifRange string, etag string, lastModified time.Time) bool {
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/") {
+		// If-Range always uses the strong comparison function
+		return etag != "" && !isWeakETag(ifRange) && !isWeakETag(etag) && ifRange == etag
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifRange)
+	return err == nil && lastModified.Truncate(time.Second).Equal(since)
+}
+
+func isWeakETag(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// etagListMatches returns true if any entity tag in the comma separated list matches etag. If strong is true then weak
+// entity tags never match.
+func etagListMatches(list string, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return etag != ""
+	}
+	if etag == "" || (strong && isWeakETag(etag)) {
+		return false
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong && isWeakETag(candidate) {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// weakETag returns a weak entity tag for a file with the given size and modification time
+func weakETag(size int64, modTime time.Time) string {
+	return fmt.Sprintf("W/\"%x-%x\"", size, modTime.UnixNano())
+}
+
+type strongETagCacheEntry struct {
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// etag returns the entity tag for the named file according to the options, or an empty string if entity tags are
+// disabled or the file could not be read
+func (m *staticMount) etag(options StaticOptions, f fs.File, name string, info fs.FileInfo, modTime time.Time) string {
+	switch options.ETag {
+	case ETagDisabled:
+		return ""
+	case ETagStrong:
+		if cached, ok := m.ETags.Load(name); ok {
+			entry := cached.(strongETagCacheEntry)
+			if entry.Size == info.Size() && entry.ModTime.Equal(modTime) {
+				return entry.ETag
+			}
+		}
+
+		seeker, canSeek := f.(io.Seeker)
+		if !canSeek {
+			return weakETag(info.Size(), modTime)
+		}
+		hash := sha256.New()
+		_, err := io.Copy(hash, f)
+		if _, seekErr := seeker.Seek(0, io.SeekStart); seekErr != nil || err != nil {
+			return ""
+		}
+		etag := "\"" + hex.EncodeToString(hash.Sum(nil)[0:16]) + "\""
+		m.ETags.Store(name, strongETagCacheEntry{Size: info.Size(), ModTime: modTime, ETag: etag})
+		return etag
+	default:
+		return weakETag(info.Size(), modTime)
+	}
+}
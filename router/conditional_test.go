@@ -0,0 +1,267 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterCheckPreconditions(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := lastModified.Add(-time.Hour).Format(http.TimeFormat)
+	after := lastModified.Add(time.Hour).Format(http.TimeFormat)
+
+	type testCase struct {
+		Method   string
+		Headers  map[string]string
+		ETag     string
+		Expected int
+	}
+
+	cases := []testCase{
+		{"GET", nil, `"a"`, 0},
+		{"GET", map[string]string{"If-None-Match": `"a"`}, `"a"`, 304},
+		{"GET", map[string]string{"If-None-Match": `"b", W/"a"`}, `"a"`, 304},
+		{"GET", map[string]string{"If-None-Match": `"b"`}, `"a"`, 0},
+		{"GET", map[string]string{"If-None-Match": "*"}, `"a"`, 304},
+		{"PUT", map[string]string{"If-None-Match": "*"}, `"a"`, 412},
+		{"PUT", map[string]string{"If-None-Match": "*"}, "", 0},
+		{"GET", map[string]string{"If-None-Match": `"b"`, "If-Modified-Since": after}, `"a"`, 0},
+		{"GET", map[string]string{"If-Modified-Since": after}, "", 304},
+		{"GET", map[string]string{"If-Modified-Since": before}, "", 0},
+		{"GET", map[string]string{"If-Modified-Since": "foobar"}, "", 0},
+		{"POST", map[string]string{"If-Modified-Since": after}, "", 0},
+		{"PUT", map[string]string{"If-Match": `"a"`}, `"a"`, 0},
+		{"PUT", map[string]string{"If-Match": `"b"`}, `"a"`, 412},
+		{"PUT", map[string]string{"If-Match": `W/"a"`}, `W/"a"`, 412},
+		{"PUT", map[string]string{"If-Match": `"a"`, "If-Unmodified-Since": before}, `"a"`, 0},
+		{"PUT", map[string]string{"If-Unmodified-Since": before}, "", 412},
+		{"PUT", map[string]string{"If-Unmodified-Since": after}, "", 0},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.Method, "/", nil)
+		if err != nil {
+			panic(err)
+		}
+		for k, v := range c.Headers {
+			req.Header.Set(k, v)
+		}
+		if actual := router.CheckPreconditions(req, c.ETag, lastModified); actual != c.Expected {
+			t.Errorf("Unexpected result for %s %v with etag '%s'. Expected %d got %d", c.Method, c.Headers, c.ETag, c.Expected, actual)
+		}
+	}
+}
+
+func TestRouterCheckIfRange(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("If-Range", `"a"`)
+	if !router.CheckIfRange(req, `"a"`, lastModified) {
+		t.Errorf("If-Range should match strong etag")
+	}
+	if router.CheckIfRange(req, `W/"a"`, lastModified) {
+		t.Errorf("If-Range should not match weak etag")
+	}
+	req.Header.Set("If-Range", lastModified.Format(http.TimeFormat))
+	if !router.CheckIfRange(req, "", lastModified) {
+		t.Errorf("If-Range should match last modified date")
+	}
+	req.Header.Set("If-Range", lastModified.Add(-time.Hour).Format(http.TimeFormat))
+	if router.CheckIfRange(req, "", lastModified) {
+		t.Errorf("If-Range should not match different date")
+	}
+}
+
+func testConditionalRequest(t *testing.T, url string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		panic(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp, string(body)
+}
+
+func TestRouterStaticETag(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/weak/")
+	options := router.DefaultStaticOptions()
+	options.ETag = router.ETagStrong
+	server.ServeFilesWithOptions(dir, "/strong/", options)
+	options.ETag = router.ETagDisabled
+	server.ServeFilesWithOptions(dir, "/none/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testConditionalRequest(t, "http://"+listenAddress+"/weak/file.txt", nil)
+	if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("Unexpected weak etag '%s'", etag)
+	}
+	resp, _ = testConditionalRequest(t, "http://"+listenAddress+"/strong/file.txt", nil)
+	if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, `"`) {
+		t.Errorf("Unexpected strong etag '%s'", etag)
+	}
+	resp, _ = testConditionalRequest(t, "http://"+listenAddress+"/none/file.txt", nil)
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		t.Errorf("Unexpected etag '%s' when disabled", etag)
+	}
+}
+
+func TestRouterStaticETagPreconditions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/weak/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testConditionalRequest(t, "http://"+listenAddress+"/weak/file.txt", nil)
+	weak := resp.Header.Get("ETag")
+
+	resp, body := testConditionalRequest(t, "http://"+listenAddress+"/weak/file.txt", map[string]string{"If-None-Match": weak})
+	if resp.StatusCode != 304 || body != "" || resp.Header.Get("ETag") != weak {
+		t.Errorf("Unexpected response for matching If-None-Match: %d %v", resp.StatusCode, resp.Header)
+	}
+	resp, _ = testConditionalRequest(t, "http://"+listenAddress+"/weak/file.txt", map[string]string{"If-Match": `"nope"`})
+	if resp.StatusCode != 412 {
+		t.Errorf("Unexpected status for failed If-Match. Expected 412 got %d", resp.StatusCode)
+	}
+}
+
+func TestRouterStaticETagIfRange(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/weak/")
+	options := router.DefaultStaticOptions()
+	options.ETag = router.ETagStrong
+	server.ServeFilesWithOptions(dir, "/strong/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testConditionalRequest(t, "http://"+listenAddress+"/strong/file.txt", nil)
+	strong := resp.Header.Get("ETag")
+	resp, _ = testConditionalRequest(t, "http://"+listenAddress+"/weak/file.txt", nil)
+	weak := resp.Header.Get("ETag")
+
+	resp, body := testConditionalRequest(t, "http://"+listenAddress+"/strong/file.txt", map[string]string{"Range": "bytes=0-4", "If-Range": strong})
+	if resp.StatusCode != 206 || body != "hello" {
+		t.Errorf("Unexpected response for matching If-Range: %d '%s'", resp.StatusCode, body)
+	}
+	resp, body = testConditionalRequest(t, "http://"+listenAddress+"/strong/file.txt", map[string]string{"Range": "bytes=0-4", "If-Range": `"old"`})
+	if resp.StatusCode != 200 || body != "hello world" {
+		t.Errorf("Unexpected response for mismatched If-Range: %d '%s'", resp.StatusCode, body)
+	}
+	resp, _ = testConditionalRequest(t, "http://"+listenAddress+"/weak/file.txt", map[string]string{"Range": "bytes=0-4", "If-Range": weak})
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected status for weak If-Range. Expected 200 got %d", resp.StatusCode)
+	}
+}
+
+func TestRouterStaticETagChanged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.ETag = router.ETagStrong
+	server.ServeFilesWithOptions(dir, "/strong/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testConditionalRequest(t, "http://"+listenAddress+"/strong/file.txt", nil)
+	strong := resp.Header.Get("ETag")
+
+	// Changing the file must change the strong etag
+	os.WriteFile(path.Join(dir, "file.txt"), []byte("goodbye world"), 0644)
+	os.Chtimes(path.Join(dir, "file.txt"), time.Now(), time.Now().Add(time.Hour))
+	resp, _ = testConditionalRequest(t, "http://"+listenAddress+"/strong/file.txt", nil)
+	if etag := resp.Header.Get("ETag"); etag == strong {
+		t.Errorf("Strong etag did not change when file changed")
+	}
+}
+
+func TestRouterServeHTTPRangeIfRange(t *testing.T) {
+	t.Parallel()
+
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/data", func(rw http.ResponseWriter, request router.Request) {
+		err := router.ServeHTTPRange(router.ServeHTTPRangeOptions{
+			Ranges:       router.ParseRangeHeader(request.HTTP.Header.Get("Range")),
+			Reader:       strings.NewReader("abcdef"),
+			TotalLength:  6,
+			MIMEType:     "text/plain",
+			Writer:       rw,
+			ETag:         `"v1"`,
+			LastModified: lastModified,
+			IfRange:      request.HTTP.Header.Get("If-Range"),
+		})
+		if err != nil {
+			t.Errorf("Unexpected error serving range: %s", err.Error())
+		}
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, body := testConditionalRequest(t, "http://"+listenAddress+"/data", map[string]string{"Range": "bytes=0-1", "If-Range": lastModified.Format(http.TimeFormat)})
+	if resp.StatusCode != 206 || body != "ab" || resp.Header.Get("ETag") != `"v1"` {
+		t.Errorf("Unexpected response for matching If-Range: %d '%s'", resp.StatusCode, body)
+	}
+
+	resp, body = testConditionalRequest(t, "http://"+listenAddress+"/data", map[string]string{"Range": "bytes=0-1", "If-Range": `"v0"`})
+	if resp.StatusCode != 200 || body != "abcdef" {
+		t.Errorf("Unexpected response for mismatched If-Range: %d '%s'", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+)
+
+// parameterConstraint describes a restriction on the value of a parameter segment
+type parameterConstraint struct {
+	// The original constraint as it appeared in the path, without the angle brackets
+	Source string
+	match  func(value string) bool
+}
+
+var builtinConstraints = map[string]func(value string) bool{
+	"int": func(value string) bool {
+		if len(value) > 1 && value[0] == '-' {
+			value = value[1:]
+		}
+		return isAllBytes(value, isDigit)
+	},
+	"uint": func(value string) bool {
+		return isAllBytes(value, isDigit)
+	},
+	"hex": func(value string) bool {
+		return isAllBytes(value, isHexDigit)
+	},
+	"alpha": func(value string) bool {
+		return isAllBytes(value, isAlpha)
+	},
+	"alnum": func(value string) bool {
+		return isAllBytes(value, func(b byte) bool { return isAlpha(b) || isDigit(b) })
+	},
+	"uuid": func(value string) bool {
+		if len(value) != 36 {
+			return false
+		}
+		for i := 0; i < len(value); i++ {
+			if i == 8 || i == 13 || i == 18 || i == 23 {
+				if value[i] != '-' {
+					return false
+				}
+			} else if !isHexDigit(value[i]) {
+				return false
+			}
+		}
+		return true
+	},
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isHexDigit(b byte) bool {
+	return isDigit(b) || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+func isAlpha(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isAllBytes(value string, fn func(b byte) bool) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if !fn(value[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// newParameterConstraint will return a constraint for the given source, which is either the name of a built-in
+// constraint or a regular expression that must match the entire value. Will panic if the regular expression is invalid.
+func newParameterConstraint(source string) *parameterConstraint {
+	if match, builtin := builtinConstraints[source]; builtin {
+		return &parameterConstraint{Source: source, match: match}
+	}
+
+	pattern, err := regexp.Compile("^(?:" + source + ")$")
+	if err != nil {
+		panic("Invalid parameter constraint <" + source + ">: " + err.Error())
+	}
+	return &parameterConstraint{Source: source, match: pattern.MatchString}
+}
+
+// sourceEqual returns true if both constraints have the same source, or if both are nil
+func (c *parameterConstraint) sourceEqual(other *parameterConstraint) bool {
+	if c == nil || other == nil {
+		return c == nil && other == nil
+	}
+	return c.Source == other.Source
+}
+
+// parseParameter will split a parameter segment, without its leading colon, into the name and constraint
+func parseParameter(segment string) (name string, constraint string) {
+	i := strings.IndexByte(segment, '<')
+	if i == -1 {
+		return segment, ""
+	}
+	if segment[len(segment)-1] != '>' || i == 0 || i == len(segment)-2 {
+		panic("Invalid parameter constraint in segment :" + segment)
+	}
+	return segment[0:i], segment[i+1 : len(segment)-1]
+}
+
+// splitPath will split a registered path, without its leading slash, into segments. Slashes within a parameter
+// constraint do not start a new segment.
+func splitPath(path string) []string {
+	segments := []string{}
+	depth := 0
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '<':
+			depth++
+		case '>':
+			if depth > 0 {
+				depth--
+			}
+		case '/':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, path[start:])
+}
@@ -0,0 +1,122 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterParameterConstraint(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/widgets/:id<int>", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("int:" + request.Parameters["id"]))
+	})
+	server.Handle("GET", "/widgets/:uuid<uuid>", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("uuid:" + request.Parameters["uuid"]))
+	})
+	server.Handle("GET", "/widgets/:slug<[a-z0-9-]+>", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("slug:" + request.Parameters["slug"]))
+	})
+	server.Handle("GET", "/widgets/:other", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("other:" + request.Parameters["other"]))
+	})
+	server.Handle("GET", "/gadgets/:id<uint>/parts", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("parts:" + request.Parameters["id"]))
+	})
+	server.Handle("GET", "/files/:name<[^/]+\\.txt>", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("text:" + request.Parameters["name"]))
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	check := func(path, expected string) {
+		resp, err := http.Get("http://" + listenAddress + path)
+		if err != nil {
+			panic(err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			panic(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Unexpected response for %s. Expected '%s' got '%s'", path, expected, data)
+		}
+	}
+
+	check("/widgets/123", "int:123")
+	check("/widgets/-123", "int:-123")
+	check("/widgets/7f1c2a8e-0b5d-4c1e-9a3f-2d4e6b8c0a1f", "uuid:7f1c2a8e-0b5d-4c1e-9a3f-2d4e6b8c0a1f")
+	check("/widgets/blue-widget", "slug:blue-widget")
+	check("/widgets/Blue_Widget", "other:Blue_Widget")
+	check("/gadgets/42/parts", "parts:42")
+	check("/files/notes.txt", "text:notes.txt")
+	testURL(t, "GET", "http://"+listenAddress+"/gadgets/-42/parts", 404)
+	testURL(t, "GET", "http://"+listenAddress+"/gadgets/abc/parts", 404)
+	testURL(t, "GET", "http://"+listenAddress+"/files/notes.pdf", 404)
+}
+
+func TestRouterParameterConstraintRemove(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/widgets/:id<int>", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(201)
+	})
+	server.Handle("GET", "/widgets/:name", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(202)
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testURL(t, "GET", "http://"+listenAddress+"/widgets/1", 201)
+	server.RemoveHandle("GET", "/widgets/:something<int>")
+	testURL(t, "GET", "http://"+listenAddress+"/widgets/1", 202)
+	server.RemoveHandle("GET", "/widgets/:something")
+	testURL(t, "GET", "http://"+listenAddress+"/widgets/1", 404)
+}
+
+func TestRouterParameterConstraintInvalid(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		recover()
+	}()
+
+	server := router.New()
+	server.Handle("GET", "/widgets/:id<[a-z>", func(rw http.ResponseWriter, request router.Request) {
+		//
+	})
+
+	t.Errorf("No panic seen when one expected for adding path with invalid constraint")
+}
+
+func TestRouterParameterConstraintNameClash(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		recover()
+	}()
+
+	server := router.New()
+	server.Handle("GET", "/widgets/:id<int>", func(rw http.ResponseWriter, request router.Request) {
+		//
+	})
+	server.Handle("GET", "/widgets/:number<int>/parts", func(rw http.ResponseWriter, request router.Request) {
+		//
+	})
+
+	t.Errorf("No panic seen when one expected for adding path with conflicting parameter name")
+}
@@ -3,11 +3,15 @@ package router
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"net/http"
-	"os"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	_ "embed"
@@ -24,38 +28,129 @@ var dirImage []byte
 //go:embed file.png
 var fileImage []byte
 
+// Columns that a directory listing can be sorted by, using the "sort" query parameter. The "order" query parameter can
+// be "asc" or "desc". Directories are always listed before files.
+const (
+	DirectoryListingSortName    = "name"
+	DirectoryListingSortSize    = "size"
+	DirectoryListingSortModTime = "mtime"
+)
+
+// DirectoryListing describes the contents of a directory listing. This is the data passed to a custom template set
+// with StaticOptions.DirectoryListingTemplate, and is the response for requests that accept "application/json".
+type DirectoryListing struct {
+	// The path of the directory relative to the static mount, always beginning with a '/'
+	Title string `json:"title"`
+	// If the directory has a parent within the static mount
+	HasParent bool `json:"has_parent"`
+	// The files and directories, sorted according to SortBy and Descending
+	Entries []DirectoryListingEntry `json:"entries"`
+	// The column the entries are sorted by
+	SortBy string `json:"sort"`
+	// If the entries are sorted in descending order
+	Descending bool `json:"descending"`
+}
+
+// DirectoryListingEntry describes a file or directory in a directory listing
+type DirectoryListingEntry struct {
+	// The name of the file or directory. Names of directories end with a '/'.
+	Name string `json:"name"`
+	// If this entry is a directory
+	IsDir bool `json:"is_dir"`
+	// The size of the file in bytes, always 0 for directories
+	Size int64 `json:"size"`
+	// The modification time of the file or directory
+	ModTime time.Time `json:"modified"`
+}
+
+// Link returns a relative link to this file or directory, with the name escaped for use in a URL path
+func (e DirectoryListingEntry) Link() string {
+	name := strings.TrimSuffix(e.Name, "/")
+	// The "./" prefix stops a name containing a ':' from being read as the scheme of an absolute URL
+	link := "./" + url.PathEscape(name)
+	if e.IsDir {
+		link += "/"
+	}
+	return link
+}
+
+// FormattedSize returns the size of the file formatted for display, such as "1.2 KiB"
+func (e DirectoryListingEntry) FormattedSize() string {
+	if e.IsDir {
+		return ""
+	}
+	return logtic.FormatBytesB(uint64(e.Size))
+}
+
+// FormattedModTime returns the modification time formatted for display
+func (e DirectoryListingEntry) FormattedModTime() string {
+	if e.ModTime.IsZero() {
+		return ""
+	}
+	return e.ModTime.UTC().Format("2006-01-02 15:04:05")
+}
+
+// SortLink returns a relative link to this listing sorted by column. If the listing is already sorted by column then
+// the link reverses the order.
+func (l DirectoryListing) SortLink(column string) string {
+	order := "asc"
+	if l.SortBy == column && !l.Descending {
+		order = "desc"
+	}
+	return "?sort=" + column + "&order=" + order
+}
+
+// sortEntries sorts the entries by the given column, listing directories before files
+func (l *DirectoryListing) sortEntries(column string, descending bool) {
+	if column != DirectoryListingSortSize && column != DirectoryListingSortModTime {
+		column = DirectoryListingSortName
+	}
+	l.SortBy = column
+	l.Descending = descending
+
+	sort.SliceStable(l.Entries, func(i, j int) bool {
+		a, b := l.Entries[i], l.Entries[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+
+		var less, equal bool
+		switch column {
+		case DirectoryListingSortSize:
+			less, equal = a.Size < b.Size, a.Size == b.Size
+		case DirectoryListingSortModTime:
+			less, equal = a.ModTime.Before(b.ModTime), a.ModTime.Equal(b.ModTime)
+		}
+		if column == DirectoryListingSortName || equal {
+			less, equal = a.Name < b.Name, a.Name == b.Name
+		}
+		if descending && !equal {
+			return !less
+		}
+		return less
+	})
+}
+
 type dirIndexTemplateType struct {
-	Title             string
-	Directories       []string
-	Files             []dirIndexFileType
+	DirectoryListing
 	IsEmpty           bool
 	FolderImageBase64 string
 	FileImageBase64   string
 }
 
-type dirIndexFileType struct {
-	Name string
-	Size string
-}
-
-func (s *impl) makeDirectoryIndex(dir, requestPath string, w http.ResponseWriter) {
+func (s *impl) makeDirectoryIndex(fsys fs.FS, dir, requestPath string, options StaticOptions, w http.ResponseWriter, req *http.Request) {
 	s.log.PDebug("Serving directory listing", map[string]interface{}{
 		"request_path":   requestPath,
 		"directory_path": dir,
 	})
 
-	title := requestPath
-	if title == "" {
-		title = "/"
+	listing := DirectoryListing{
+		Title:     "/" + requestPath,
+		HasParent: requestPath != "",
+		Entries:   []DirectoryListingEntry{},
 	}
 
-	templateData := dirIndexTemplateType{
-		Title:             title,
-		FolderImageBase64: base64.StdEncoding.EncodeToString(dirImage),
-		FileImageBase64:   base64.StdEncoding.EncodeToString(fileImage),
-	}
-
-	entries, err := os.ReadDir(dir)
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		s.log.PError("Error reading directory", map[string]interface{}{
 			"dir":   dir,
@@ -65,45 +160,81 @@ func (s *impl) makeDirectoryIndex(dir, requestPath string, w http.ResponseWriter
 		return
 	}
 	for _, entry := range entries {
+		if options.isHidden(entry.Name()) {
+			continue
+		}
+
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
 
 		if entry.IsDir() {
-			templateData.Directories = append(templateData.Directories, entry.Name()+"/")
+			listing.Entries = append(listing.Entries, DirectoryListingEntry{
+				Name:    entry.Name() + "/",
+				IsDir:   true,
+				ModTime: info.ModTime(),
+			})
 		} else {
-			templateData.Files = append(templateData.Files, dirIndexFileType{
-				Name: entry.Name(),
-				Size: logtic.FormatBytesB(uint64(info.Size())),
+			listing.Entries = append(listing.Entries, DirectoryListingEntry{
+				Name:    entry.Name(),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
 			})
 		}
 	}
-	if len(entries) == 0 {
-		templateData.IsEmpty = true
-	}
-
-	t, err := template.New("index").Parse(dirIndex)
-	if err != nil {
-		s.log.PError("Error forming template for directory index", map[string]interface{}{
-			"error": err.Error(),
-		})
-		w.WriteHeader(500)
-		return
-	}
+	query := req.URL.Query()
+	listing.sortEntries(query.Get("sort"), query.Get("order") == "desc")
 
 	buf := &bytes.Buffer{}
-	if err := t.ExecuteTemplate(buf, "main", templateData); err != nil {
-		s.log.PError("Error executing template for directory index", map[string]interface{}{
-			"error": err.Error(),
-		})
-		w.WriteHeader(500)
-		return
+	contentType := "text/html; charset=utf-8"
+	if strings.Contains(req.Header.Get("Accept"), "application/json") {
+		contentType = "application/json"
+		if err := json.NewEncoder(buf).Encode(listing); err != nil {
+			s.log.PError("Error encoding directory index", map[string]interface{}{
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+	} else if options.DirectoryListingTemplate != nil {
+		if err := options.DirectoryListingTemplate.Execute(buf, listing); err != nil {
+			s.log.PError("Error executing custom template for directory index", map[string]interface{}{
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+	} else {
+		t, err := template.New("index").Parse(dirIndex)
+		if err != nil {
+			s.log.PError("Error forming template for directory index", map[string]interface{}{
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+
+		templateData := dirIndexTemplateType{
+			DirectoryListing:  listing,
+			IsEmpty:           len(listing.Entries) == 0,
+			FolderImageBase64: base64.StdEncoding.EncodeToString(dirImage),
+			FileImageBase64:   base64.StdEncoding.EncodeToString(fileImage),
+		}
+		if err := t.ExecuteTemplate(buf, "main", templateData); err != nil {
+			s.log.PError("Error executing template for directory index", map[string]interface{}{
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
 	}
 
-	w.WriteHeader(200)
-	w.Header().Add("Content-Type", "text/html; charset=utf-8")
+	options.setHeaders(w)
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", buf.Len()))
 	w.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(200)
 	io.Copy(w, buf)
 }
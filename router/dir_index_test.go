@@ -1,8 +1,13 @@
 package router_test
 
 import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -65,3 +70,177 @@ func TestDirectoryIndexDisabled(t *testing.T) {
 
 	router.GenerateDirectoryListing = true
 }
+
+func testDirectoryListingRequest(url string, accept string) (*http.Response, string) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		panic(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	return resp, string(body)
+}
+
+func testDirectoryListing(t *testing.T, url string) router.DirectoryListing {
+	resp, body := testDirectoryListingRequest(url, "application/json")
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected response for JSON listing '%s': %d %v", url, resp.StatusCode, resp.Header)
+	}
+	listing := router.DirectoryListing{}
+	if err := json.Unmarshal([]byte(body), &listing); err != nil {
+		t.Fatalf("Error decoding JSON listing: %s", err.Error())
+	}
+	return listing
+}
+
+func makeDirectoryListingDir(t *testing.T) string {
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "b.txt"), []byte("bb"), 0644)
+	os.WriteFile(path.Join(dir, "a.txt"), []byte("aaa"), 0644)
+	os.WriteFile(path.Join(dir, "c.txt"), []byte("c"), 0644)
+	os.Mkdir(path.Join(dir, "sub"), 0755)
+	os.Mkdir(path.Join(dir, "sub", "inner"), 0755)
+	now := time.Now()
+	os.Chtimes(path.Join(dir, "a.txt"), now, now.Add(-3*time.Hour))
+	os.Chtimes(path.Join(dir, "b.txt"), now, now.Add(-1*time.Hour))
+	os.Chtimes(path.Join(dir, "c.txt"), now, now.Add(-2*time.Hour))
+	return dir
+}
+
+func TestDirectoryIndexListingSort(t *testing.T) {
+	t.Parallel()
+
+	dir := makeDirectoryListingDir(t)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/example/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	check := func(query string, expected string) {
+		names := []string{}
+		for _, entry := range testDirectoryListing(t, "http://"+listenAddress+"/example/"+query).Entries {
+			names = append(names, entry.Name)
+		}
+		if actual := strings.Join(names, ","); actual != expected {
+			t.Errorf("Unexpected order for '%s'. Expected '%s' got '%s'", query, expected, actual)
+		}
+	}
+
+	check("", "sub/,a.txt,b.txt,c.txt")
+	check("?sort=name&order=desc", "sub/,c.txt,b.txt,a.txt")
+	check("?sort=size", "sub/,c.txt,b.txt,a.txt")
+	check("?sort=size&order=desc", "sub/,a.txt,b.txt,c.txt")
+	check("?sort=mtime", "sub/,a.txt,c.txt,b.txt")
+	check("?sort=invalid", "sub/,a.txt,b.txt,c.txt")
+}
+
+func TestDirectoryIndexListingJSON(t *testing.T) {
+	t.Parallel()
+
+	dir := makeDirectoryListingDir(t)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/example/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	root := testDirectoryListing(t, "http://"+listenAddress+"/example/")
+	if root.HasParent || root.Title != "/" || root.Entries[1].Size != 3 || root.Entries[1].ModTime.IsZero() {
+		t.Errorf("Unexpected root listing: %+v", root)
+	}
+	if sub := testDirectoryListing(t, "http://"+listenAddress+"/example/sub/"); !sub.HasParent || sub.Title != "/sub/" {
+		t.Errorf("Unexpected sub listing: %+v", sub)
+	}
+}
+
+func TestDirectoryIndexListingHTML(t *testing.T) {
+	t.Parallel()
+
+	dir := makeDirectoryListingDir(t)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/example/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, body := testDirectoryListingRequest("http://"+listenAddress+"/example/sub/", "text/html")
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" || !strings.Contains(body, `href="../"`) || !strings.Contains(body, "inner/") {
+		t.Errorf("Unexpected HTML listing: %d %s", resp.StatusCode, body)
+	}
+	if _, body := testDirectoryListingRequest("http://"+listenAddress+"/example/", ""); strings.Contains(body, `href="../"`) {
+		t.Errorf("Root listing should not link to parent")
+	}
+}
+
+func TestDirectoryIndexListingTemplate(t *testing.T) {
+	t.Parallel()
+
+	dir := makeDirectoryListingDir(t)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.DirectoryListingTemplate = template.Must(template.New("custom").Parse(`{{.Title}}:{{range .Entries}}{{.Name}},{{end}}`))
+	server.ServeFilesWithOptions(dir, "/custom/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	if _, body := testDirectoryListingRequest("http://"+listenAddress+"/custom/", ""); body != "/:sub/,a.txt,b.txt,c.txt," {
+		t.Errorf("Unexpected custom listing '%s'", body)
+	}
+}
+
+func TestDirectoryIndexListingEscaped(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	names := []string{"a#b.txt", "what?.txt", "x:y.txt", "100%.txt", "space name.txt"}
+	for _, name := range names {
+		os.WriteFile(path.Join(dir, name), []byte(name), 0644)
+	}
+	os.Mkdir(path.Join(dir, "sub#dir"), 0755)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/example/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	_, body := testDirectoryListingRequest("http://"+listenAddress+"/example/", "text/html")
+	for _, link := range []string{`href="./a%23b.txt"`, `href="./what%3F.txt"`, `href="./x:y.txt"`, `href="./100%25.txt"`, `href="./space%20name.txt"`, `href="./sub%23dir/"`} {
+		if !strings.Contains(body, link) {
+			t.Errorf("Directory listing missing escaped link '%s'", link)
+		}
+	}
+
+	for _, name := range names {
+		resp, content := testDirectoryListingRequest("http://"+listenAddress+"/example/"+(&router.DirectoryListingEntry{Name: name}).Link(), "")
+		if resp.StatusCode != 200 || content != name {
+			t.Errorf("Unexpected response following link for '%s': %d '%s'", name, resp.StatusCode, content)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterStaticFallback(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"index.html":          {Data: []byte("shell")},
+		"assets/app.1a2b.js":  {Data: []byte("app")},
+		"assets/.hidden.html": {Data: []byte("hidden")},
+	}
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.FallbackFile = "/index.html"
+	options.CacheMaxAge = 365 * 24 * time.Hour
+	options.GenerateDirectoryListing = false
+	server.ServeFSWithOptions(fsys, "/app/", options)
+	server.Handle("GET", "/api/users", func(w http.ResponseWriter, r router.Request) {
+		w.WriteHeader(200)
+	})
+
+	type testCase struct {
+		Path         string
+		Accept       string
+		Status       int
+		Body         string
+		CacheControl string
+	}
+
+	cases := []testCase{
+		{"/app/", "", 200, "shell", "max-age=31536000; public"},
+		{"/app/users/1", "", 200, "shell", "no-cache"},
+		{"/app/users/", "", 200, "shell", "no-cache"},
+		{"/app/assets/", "", 200, "shell", "no-cache"},
+		{"/app/assets", "", 200, "shell", "no-cache"},
+		{"/app/users/1.json", "text/html,application/xhtml+xml", 200, "shell", "no-cache"},
+		{"/app/assets/app.1a2b.js", "", 200, "app", "max-age=31536000; public"},
+		{"/app/assets/missing.js", "*/*", 404, "", ""},
+		{"/app/assets/.hidden.html", "text/html", 404, "", ""},
+		{"/api/missing", "text/html", 404, "", ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.Path, nil)
+		if c.Accept != "" {
+			req.Header.Set("Accept", c.Accept)
+		}
+		resp := httptest.NewRecorder()
+		server.Handler().ServeHTTP(resp, req)
+
+		if resp.Code != c.Status {
+			t.Errorf("Unexpected status for '%s'. Expected %d got %d", c.Path, c.Status, resp.Code)
+			continue
+		}
+		if c.Status != 200 {
+			continue
+		}
+		if body := resp.Body.String(); body != c.Body {
+			t.Errorf("Unexpected body for '%s'. Expected '%s' got '%s'", c.Path, c.Body, body)
+		}
+		if cacheControl := resp.Header().Get("Cache-Control"); cacheControl != c.CacheControl {
+			t.Errorf("Unexpected cache control for '%s'. Expected '%s' got '%s'", c.Path, c.CacheControl, cacheControl)
+		}
+	}
+}
+
+func TestRouterStaticFallbackInvalid(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("No panic seen for invalid fallback file")
+		}
+	}()
+
+	options := router.DefaultStaticOptions()
+	options.FallbackFile = "../index.html"
+	router.New().ServeFSWithOptions(fstest.MapFS{}, "/app/", options)
+}
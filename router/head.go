@@ -0,0 +1,88 @@
+package router
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// headResponseWriter is used when a HEAD request is answered by a GET handle. Any data written by the handle is
+// discarded, but counted, so that the response includes the same status, headers, and content length that the GET
+// response would have had.
+type headResponseWriter struct {
+	w      http.ResponseWriter
+	status int
+	length int64
+}
+
+func (h *headResponseWriter) Header() http.Header {
+	return h.w.Header()
+}
+
+func (h *headResponseWriter) WriteHeader(statusCode int) {
+	if h.status != 0 {
+		return
+	}
+	h.status = statusCode
+}
+
+func (h *headResponseWriter) Write(p []byte) (int, error) {
+	if h.status == 0 {
+		h.status = 200
+	}
+	h.length += int64(len(p))
+	return len(p), nil
+}
+
+// Flush does nothing, as no body is sent the headers are only written once the handle has returned
+func (h *headResponseWriter) Flush() {}
+
+func (h *headResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := h.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController
+func (h *headResponseWriter) Unwrap() http.ResponseWriter {
+	return h.w
+}
+
+// finish writes the status and computed content length to the underlying writer. It must be called once the handle
+// has returned.
+func (h *headResponseWriter) finish() {
+	if h.status == 0 {
+		h.status = 200
+	}
+	bodyAllowed := h.status >= 200 && h.status != 204 && h.status != 304
+	if bodyAllowed && h.w.Header().Get("Content-Length") == "" {
+		h.w.Header().Set("Content-Length", strconv.FormatInt(h.length, 10))
+	}
+	h.w.WriteHeader(h.status)
+}
+
+// headHandle wraps a GET handle so that it can answer a HEAD request
+func headHandle(handle Handle) Handle {
+	return func(w http.ResponseWriter, r Request) {
+		hw := &headResponseWriter{w: w}
+		handle(hw, r)
+		hw.finish()
+	}
+}
+
+// handleForMethod returns the handle registered for method on this node. HEAD requests fall back to the GET handle
+// when no HEAD handle was registered.
+func (n *node) handleForMethod(method string) (Handle, bool) {
+	if route, present := n.Methods[method]; present {
+		return route.Handle, true
+	}
+	if method == "HEAD" {
+		if route, present := n.Methods["GET"]; present {
+			return headHandle(route.Handle), true
+		}
+	}
+	return nil, false
+}
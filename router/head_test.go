@@ -0,0 +1,136 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterHeadFallback(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/hello", func(rw http.ResponseWriter, request router.Request) {
+		rw.Header().Set("X-Greeting", "hi")
+		rw.WriteHeader(202)
+		rw.Write([]byte("Hello world"))
+	})
+	server.Handle("GET", "/files/*path", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte(request.Parameters["path"]))
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, err := http.Head("http://" + listenAddress + "/hello")
+	if err != nil {
+		panic(err)
+	}
+	if resp.StatusCode != 202 {
+		t.Errorf("Unexpected status code. Expected %d got %d", 202, resp.StatusCode)
+	}
+	if resp.ContentLength != 11 {
+		t.Errorf("Unexpected content length. Expected %d got %d", 11, resp.ContentLength)
+	}
+	if resp.Header.Get("X-Greeting") != "hi" {
+		t.Errorf("Missing header from GET handle")
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) > 0 {
+		t.Errorf("Unexpected body for HEAD request")
+	}
+
+	resp, err = http.Head("http://" + listenAddress + "/files/a/b/c")
+	if err != nil {
+		panic(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+	if resp.ContentLength != 5 {
+		t.Errorf("Unexpected content length. Expected %d got %d", 5, resp.ContentLength)
+	}
+
+	testURL(t, "POST", "http://"+listenAddress+"/hello", 405)
+}
+
+func TestRouterHeadExplicitHandle(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/hello", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(200)
+	})
+	server.Handle("HEAD", "/hello", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(204)
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testURL(t, "HEAD", "http://"+listenAddress+"/hello", 204)
+}
+
+func TestRouterHeadWithoutGet(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("POST", "/hello", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(200)
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testURL(t, "HEAD", "http://"+listenAddress+"/hello", 405)
+}
+
+func TestRouterHeadEmptyBody(t *testing.T) {
+	t.Parallel()
+
+	server := router.New()
+	server.Handle("GET", "/empty", func(rw http.ResponseWriter, request router.Request) {})
+
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, httptest.NewRequest("HEAD", "/empty", nil))
+	if resp.Code != 200 || resp.Header().Get("Content-Length") != "0" {
+		t.Errorf("Unexpected HEAD response for empty body: %d %v", resp.Code, resp.Header())
+	}
+}
+
+func TestRouterHeadFlusher(t *testing.T) {
+	t.Parallel()
+
+	server := router.New()
+	server.Handle("GET", "/stream", func(rw http.ResponseWriter, request router.Request) {
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			rw.WriteHeader(500)
+			return
+		}
+		rw.Write([]byte("chunk"))
+		flusher.Flush()
+		if err := http.NewResponseController(rw).Flush(); err != nil {
+			rw.WriteHeader(500)
+		}
+	})
+
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, httptest.NewRequest("HEAD", "/stream", nil))
+	if resp.Code != 200 || resp.Header().Get("Content-Length") != "5" || resp.Body.Len() != 0 {
+		t.Errorf("Unexpected HEAD response for flushed body: %d %v", resp.Code, resp.Header())
+	}
+}
@@ -0,0 +1,165 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var errVirtualHostServe = fmt.Errorf("virtual host servers can not serve on their own, serve the server they were created from")
+
+// wildcardHost describes a routing table for all hosts ending with a suffix
+type wildcardHost struct {
+	// The suffix of the host, including the leading dot
+	Suffix string
+	// The name of the parameter for the portion of the host before the suffix
+	Parameter string
+	impl      *impl
+}
+
+// Host returns a server with its own routing table that will be used for requests to the given host. Requests for
+// hosts without their own routing table use the routing table of this server.
+//
+// The pattern is either an exact host name, such as "example.com", or a wildcard, such as "*.example.com", which
+// matches any host ending with ".example.com". The portion of the host matched by the wildcard is included in the
+// request parameters as "host", or with the name that follows the asterisk, such as "*tenant.example.com". Exact hosts
+// take priority over wildcards, and longer wildcards take priority over shorter ones. Hosts are not case sensitive and
+// any port in the request is ignored.
+//
+// The returned server shares the listener of this server and can not be served on its own. Until a handle is set on
+// the returned server, requests that do not match any of its routes use the NotFound and MethodNotAllowed handles of
+// this server. Calling Host more than once with the same pattern returns a server for the same routing table.
+//
+// Will panic if the pattern is empty or invalid.
+func (s *Server) Host(pattern string) *Server {
+	root := s.impl
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	pattern = strings.ToLower(pattern)
+	exact, wildcard := parseHostPattern(pattern)
+
+	root.Lock.Lock()
+	defer root.Lock.Unlock()
+
+	if exact != "" {
+		if existing, ok := root.Hosts[exact]; ok {
+			return &Server{impl: existing}
+		}
+	} else {
+		for _, host := range root.WildcardHosts {
+			if host.Suffix != wildcard.Suffix {
+				continue
+			}
+			if host.Parameter != wildcard.Parameter {
+				panic("Host pattern '" + pattern + "' collides with existing pattern " + host.impl.HostPattern)
+			}
+			return &Server{impl: host.impl}
+		}
+	}
+
+	hostImpl := &impl{
+		Lock:        &sync.RWMutex{},
+		Index:       newNode(""),
+		Names:       map[string]*namedRoute{},
+		HostPattern: pattern,
+		Parent:      root,
+		log:         root.log,
+	}
+
+	if exact != "" {
+		root.Hosts[exact] = hostImpl
+	} else {
+		wildcard.impl = hostImpl
+		root.WildcardHosts = append(root.WildcardHosts, wildcard)
+		sort.SliceStable(root.WildcardHosts, func(i, j int) bool {
+			return len(root.WildcardHosts[i].Suffix) > len(root.WildcardHosts[j].Suffix)
+		})
+	}
+	root.log.PDebug("Add virtual host", map[string]interface{}{
+		"host": pattern,
+	})
+
+	return &Server{impl: hostImpl}
+}
+
+// parseHostPattern will return either the exact host name or the wildcard host for the pattern
+func parseHostPattern(pattern string) (string, *wildcardHost) {
+	if pattern == "" {
+		panic("Host pattern can not be empty")
+	}
+	if strings.ContainsAny(pattern, "/: ") {
+		panic("Invalid host pattern '" + pattern + "'")
+	}
+	if pattern[0] != '*' {
+		return strings.TrimSuffix(pattern, "."), nil
+	}
+
+	dot := strings.IndexByte(pattern, '.')
+	if dot == -1 || dot == len(pattern)-1 || strings.ContainsRune(pattern[1:], '*') {
+		panic("Invalid host pattern '" + pattern + "'")
+	}
+	parameter := pattern[1:dot]
+	if parameter == "" {
+		parameter = "host"
+	}
+	return "", &wildcardHost{
+		Suffix:    strings.TrimSuffix(pattern[dot:], "."),
+		Parameter: parameter,
+	}
+}
+
+// normalizeHost will lowercase the host and remove any port and trailing dot
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// hostFor returns the routing table for the given host, and the host parameter if the host matched a wildcard. Returns
+// nil if the host does not have its own routing table.
+func (s *impl) hostFor(host string) (*impl, parameterValue) {
+	s.Lock.RLock()
+	defer s.Lock.RUnlock()
+
+	if len(s.Hosts) == 0 && len(s.WildcardHosts) == 0 {
+		return nil, parameterValue{}
+	}
+
+	host = normalizeHost(host)
+	if hostImpl, ok := s.Hosts[host]; ok {
+		return hostImpl, parameterValue{}
+	}
+	for _, wildcard := range s.WildcardHosts {
+		if len(host) > len(wildcard.Suffix) && strings.HasSuffix(host, wildcard.Suffix) {
+			return wildcard.impl, parameterValue{wildcard.Parameter, host[0 : len(host)-len(wildcard.Suffix)]}
+		}
+	}
+	return nil, parameterValue{}
+}
+
+// notFound will call the NotFound handle for this routing table, or the handle of its parent if it has none
+func (s *impl) notFound(w http.ResponseWriter, req *http.Request) {
+	handle := s.NotFoundHandle
+	if handle == nil && s.Parent != nil {
+		s.Parent.notFound(w, req)
+		return
+	}
+	handle(w, req)
+}
+
+// methodNotAllowed will call the MethodNotAllowed handle for this routing table, or the handle of its parent if it has
+// none
+func (s *impl) methodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	handle := s.MethodNotAllowedHandle
+	if handle == nil && s.Parent != nil {
+		s.Parent.methodNotAllowed(w, req)
+		return
+	}
+	handle(w, req)
+}
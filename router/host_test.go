@@ -0,0 +1,116 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterHost(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("default"))
+	})
+	server.SetNotFoundHandle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("default not found"))
+	})
+
+	example := server.Host("Example.com")
+	example.Handle("GET", "/", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("example"))
+	})
+
+	tenants := server.Host("*tenant.example.com")
+	tenants.Handle("GET", "/users/:username", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("tenant " + request.Parameters["tenant"] + " user " + request.Parameters["username"]))
+	})
+	tenants.SetNotFoundHandle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("tenant not found"))
+	})
+
+	api := server.Host("*.api.example.com")
+	api.Handle("GET", "/", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("api " + request.Parameters["host"]))
+	})
+
+	if server.Host("example.com") == nil {
+		t.Errorf("No server returned for existing host")
+	}
+
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	check := func(host, path string, expectedStatus int, expected string) {
+		req, err := http.NewRequest("GET", "http://"+listenAddress+path, nil)
+		if err != nil {
+			panic(err)
+		}
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			panic(err)
+		}
+		if resp.StatusCode != expectedStatus {
+			t.Errorf("Unexpected status code for %s%s. Expected %d got %d", host, path, expectedStatus, resp.StatusCode)
+		}
+		if string(data) != expected {
+			t.Errorf("Unexpected response for %s%s. Expected '%s' got '%s'", host, path, expected, data)
+		}
+	}
+
+	check("other.com", "/", 200, "default")
+	check("example.com", "/", 200, "example")
+	check("EXAMPLE.com:8080", "/", 200, "example")
+	check("example.com.", "/", 200, "example")
+	check("example.com", "/nothing", 404, "default not found")
+	check("acme.example.com", "/users/ian", 200, "tenant acme user ian")
+	check("acme.example.com", "/", 404, "tenant not found")
+	check("eu.api.example.com", "/", 200, "api eu")
+	check("api.example.com", "/", 404, "tenant not found")
+
+	routes := server.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("Unexpected number of routes. Expected 4 got %d", len(routes))
+	}
+	if routes[0].Host != "" || routes[1].Host != "*.api.example.com" || routes[3].Host != "example.com" {
+		t.Errorf("Unexpected hosts for routes: %+v", routes)
+	}
+
+	if err := example.Serve(nil); err == nil {
+		t.Errorf("No error seen when serving a virtual host")
+	}
+}
+
+func TestRouterInvalidHost(t *testing.T) {
+	t.Parallel()
+
+	check := func(pattern string) {
+		defer func() {
+			recover()
+		}()
+
+		router.New().Host(pattern)
+		t.Errorf("No panic seen when one expected for host pattern '%s'", pattern)
+	}
+
+	check("")
+	check("*")
+	check("*example")
+	check("example.com/")
+	check("*.*.example.com")
+}
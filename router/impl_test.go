@@ -15,13 +15,70 @@ func TestImpl(t *testing.T) {
 	if len(s.impl.Index.Methods) > 0 {
 		t.Errorf("No methods should be present on the index")
 	}
-	if len(s.impl.Index.Children) != 2 {
+	if len(s.impl.Index.Children) != 1 {
 		t.Errorf("Incorrect number of children on the index")
 	}
 
 	// Asset that these exact children are available, this will easily panic if they are not
-	s.impl.Index.Children[pathKeyIndex].Methods["GET"](nil, Request{})
-	s.impl.Index.Children["dir"].Methods["GET"](nil, Request{})
-	s.impl.Index.Children["dir"].Children[pathKeyIndex].Methods["GET"](nil, Request{})
-	s.impl.Index.Children["dir"].Children["file"].Methods["GET"](nil, Request{})
+	root := s.impl.Index.Children[0]
+	if root.Path != "/" {
+		t.Errorf("Unexpected path for root node '%s'", root.Path)
+	}
+	root.Methods["GET"].Handle(nil, Request{})
+	dir := root.Children[0]
+	if dir.Path != "dir" {
+		t.Errorf("Unexpected path for dir node '%s'", dir.Path)
+	}
+	dir.Methods["GET"].Handle(nil, Request{})
+	dirIndex := dir.Children[0]
+	if dirIndex.Path != "/" {
+		t.Errorf("Unexpected path for dir index node '%s'", dirIndex.Path)
+	}
+	dirIndex.Methods["GET"].Handle(nil, Request{})
+	dirIndex.Children[0].Methods["GET"].Handle(nil, Request{})
+}
+
+func TestImplSplitAndMerge(t *testing.T) {
+	s := New()
+	s.Handle("GET", "/users/all", func(rw http.ResponseWriter, r Request) { /* */ })
+	s.Handle("GET", "/users/allow", func(rw http.ResponseWriter, r Request) { /* */ })
+	s.Handle("GET", "/users/alternate", func(rw http.ResponseWriter, r Request) { /* */ })
+
+	users := s.impl.Index.Children[0]
+	if users.Path != "/users/al" {
+		t.Fatalf("Unexpected path for split node '%s'", users.Path)
+	}
+	if users.Indices != "lt" {
+		t.Fatalf("Unexpected indices for split node '%s'", users.Indices)
+	}
+
+	s.RemoveHandle("GET", "/users/alternate")
+	s.RemoveHandle("GET", "/users/all")
+	users = s.impl.Index.Children[0]
+	if users.Path != "/users/allow" {
+		t.Fatalf("Unexpected path for merged node '%s'", users.Path)
+	}
+
+	s.RemoveHandle("GET", "/users/allow")
+	if !s.impl.Index.isEmpty() {
+		t.Fatalf("Index not empty after removing all handles")
+	}
+}
+
+func TestImplStaticZeroAllocations(t *testing.T) {
+	s := newBenchmarkServer()
+	w := &benchmarkResponseWriter{header: http.Header{}}
+
+	for _, path := range []string{"/", "/about", "/users/all/settings", "/api/v1/status"} {
+		req, err := http.NewRequest("GET", "http://localhost"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		allocs := testing.AllocsPerRun(100, func() {
+			s.impl.ServeHTTP(w, req)
+		})
+		if allocs > 0 {
+			t.Errorf("Unexpected allocations for static path '%s': %f", path, allocs)
+		}
+	}
 }
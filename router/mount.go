@@ -0,0 +1,106 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// mount describes a http.Handler mounted under a path prefix
+type mount struct {
+	Prefix  string
+	Handler http.Handler
+}
+
+// Mount will pass all requests for prefix, or any path below prefix, to handler regardless of the method. The prefix
+// is removed from the request path before it is passed to handler, so a request for "/admin/users" to a handler
+// mounted at "/admin" will have the path "/users". The original path is still available from the RequestURI of the
+// request.
+//
+// Mounted handlers take priority over any handles registered with Handle or ServeFiles, and longer prefixes take
+// priority over shorter ones. Any trailing slash on prefix is ignored.
+//
+// Will panic if prefix does not begin with a '/', is the root path, or if a handler is already mounted at prefix.
+//
+// For example:
+//
+//	server.Mount("/files", http.FileServer(http.Dir("/srv/files")))
+func (s *Server) Mount(prefix string, handler http.Handler) {
+	if len(prefix) == 0 || prefix[0] != '/' {
+		panic("Mount prefix must begin with a '/'")
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		panic("Can not mount a handler at the root path")
+	}
+
+	s.impl.Lock.Lock()
+	defer s.impl.Lock.Unlock()
+
+	for _, m := range s.impl.Mounts {
+		if m.Prefix == prefix {
+			panic("Duplicate handler mounted at " + prefix)
+		}
+	}
+
+	s.impl.Mounts = append(s.impl.Mounts, &mount{Prefix: prefix, Handler: handler})
+	sort.SliceStable(s.impl.Mounts, func(i, j int) bool {
+		return len(s.impl.Mounts[i].Prefix) > len(s.impl.Mounts[j].Prefix)
+	})
+	s.impl.log.PDebug("Mount handler", map[string]interface{}{
+		"prefix": prefix,
+	})
+}
+
+// Unmount will remove the handler mounted at prefix. Does nothing if no handler is mounted at prefix.
+func (s *Server) Unmount(prefix string) {
+	prefix = strings.TrimRight(prefix, "/")
+
+	s.impl.Lock.Lock()
+	defer s.impl.Lock.Unlock()
+
+	for i, m := range s.impl.Mounts {
+		if m.Prefix == prefix {
+			s.impl.Mounts = append(s.impl.Mounts[:i], s.impl.Mounts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Handler returns a http.Handler for the server, for use with other HTTP servers or with httptest. Requests are
+// routed exactly as they would be if the server was listening with Serve, including to any virtual hosts.
+func (s *Server) Handler() http.Handler {
+	return s.impl
+}
+
+// mountFor returns the mount matching path, if any
+func (s *impl) mountFor(path string) *mount {
+	for _, m := range s.Mounts {
+		if path == m.Prefix || (strings.HasPrefix(path, m.Prefix) && path[len(m.Prefix)] == '/') {
+			return m
+		}
+	}
+	return nil
+}
+
+// stripPrefix returns a shallow copy of req with prefix removed from the path
+func stripPrefix(req *http.Request, prefix string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+	if r.URL.Path == "" {
+		r.URL.Path = "/"
+	}
+	if strings.HasPrefix(req.URL.RawPath, prefix) {
+		r.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefix)
+		if r.URL.RawPath == "" {
+			r.URL.RawPath = "/"
+		}
+	} else {
+		r.URL.RawPath = ""
+	}
+	return r
+}
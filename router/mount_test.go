@@ -0,0 +1,80 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterMount(t *testing.T) {
+	t.Parallel()
+
+	echoPath := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.Method + " " + r.URL.Path))
+		})
+	}
+
+	server := router.New()
+	server.Handle("GET", "/admin/login", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("handle"))
+	})
+	server.Handle("GET", "/administrator", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("administrator"))
+	})
+	server.Mount("/admin/", echoPath("admin"))
+	server.Mount("/admin/files", echoPath("files"))
+
+	check := func(method, path, expected string) {
+		resp := httptest.NewRecorder()
+		server.Handler().ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+		data, _ := io.ReadAll(resp.Body)
+		if string(data) != expected {
+			t.Errorf("Unexpected response for %s %s. Expected '%s' got '%s'", method, path, expected, data)
+		}
+	}
+
+	check("GET", "/admin", "admin GET /")
+	check("POST", "/admin/", "admin POST /")
+	check("GET", "/admin/login", "admin GET /login")
+	check("DELETE", "/admin/users/1", "admin DELETE /users/1")
+	check("GET", "/admin/files/a%2Fb", "files GET /a/b")
+	check("GET", "/administrator", "administrator")
+
+	found := false
+	for _, route := range server.Routes() {
+		if route.Kind == router.RouteKindMount && route.Path == "/admin/files" && route.Method == "*" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Mounted handler not included in routes")
+	}
+
+	server.Unmount("/admin")
+	check("GET", "/admin/login", "handle")
+	check("GET", "/admin/files/a", "files GET /a")
+}
+
+func TestRouterMountInvalid(t *testing.T) {
+	t.Parallel()
+
+	check := func(prefix string) {
+		defer func() {
+			recover()
+		}()
+
+		server := router.New()
+		server.Mount("/duplicate", http.NotFoundHandler())
+		server.Mount(prefix, http.NotFoundHandler())
+		t.Errorf("No panic seen when one expected for mount prefix '%s'", prefix)
+	}
+
+	check("")
+	check("admin")
+	check("/")
+	check("/duplicate/")
+}
@@ -0,0 +1,193 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PathPolicy describes how the router treats request paths that do not exactly match a registered path. All options
+// are disabled by default.
+//
+// Redirects use a 301 status for GET and HEAD requests, and a 308 status for all other methods so that clients repeat
+// the request with the same method and body. The query string of the request is preserved in the redirect.
+type PathPolicy struct {
+	// RedirectTrailingSlash will redirect requests for a path that has no handle to the same path with or without a
+	// trailing slash, if a handle is registered for that variant.
+	RedirectTrailingSlash bool
+	// CleanPath will redirect requests for paths that contain empty, '.', or '..' segments to the cleaned path before
+	// matching. Handlers mounted with Mount will only receive clean paths.
+	CleanPath bool
+	// CaseInsensitive will redirect requests for a path that has no handle to the registered path that matches without
+	// regard to case, if any. Only static segments are compared without regard to case, parameter values are kept as
+	// they were in the request.
+	CaseInsensitive bool
+}
+
+// SetPathPolicy will set the policy for request paths that do not exactly match a registered path. Virtual hosts use
+// the policy of the server they were created from unless they have their own.
+//
+// SetPathPolicy may be called even while the server is listening and is threadsafe.
+func (s *Server) SetPathPolicy(policy PathPolicy) {
+	s.impl.Lock.Lock()
+	s.impl.PathPolicy = &policy
+	s.impl.Lock.Unlock()
+}
+
+// pathPolicy returns the path policy for this routing table, or the policy of its parent if it has none
+func (s *impl) pathPolicy() PathPolicy {
+	if s.PathPolicy == nil {
+		if s.Parent != nil {
+			s.Parent.Lock.RLock()
+			defer s.Parent.Lock.RUnlock()
+			return s.Parent.pathPolicy()
+		}
+		return PathPolicy{}
+	}
+	return *s.PathPolicy
+}
+
+// cleanPath returns the canonical form of p, eliminating empty, '.', and '..' segments while preserving any trailing
+// slash
+func cleanPath(p string) string {
+	cleaned := path.Clean(p)
+	if cleaned != "/" && p[len(p)-1] == '/' {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// canonicalPath returns the registered variant of p according to policy, or an empty string if there is none. Must be
+// called while holding at least a read lock.
+func (s *impl) canonicalPath(p string, method string, policy PathPolicy) string {
+	// A path beginning with two slashes would be treated as a host name by clients
+	if strings.HasPrefix(p, "//") {
+		return ""
+	}
+
+	candidates := []string{}
+	if policy.RedirectTrailingSlash && p != "/" {
+		if strings.HasSuffix(p, "/") {
+			candidates = append(candidates, p[:len(p)-1])
+		} else {
+			candidates = append(candidates, p+"/")
+		}
+	}
+
+	for _, candidate := range candidates {
+		if s.pathExists(candidate, method) {
+			return candidate
+		}
+	}
+
+	if policy.CaseInsensitive {
+		candidates = append([]string{p}, candidates...)
+		for _, candidate := range candidates {
+			if fixed, found := s.Index.findCaseInsensitive(candidate); found {
+				return fixed
+			}
+		}
+	}
+
+	return ""
+}
+
+// pathExists returns true if any handle is registered for the path
+func (s *impl) pathExists(p string, method string) bool {
+	parameters := parameterPool.Get().(*[]parameterValue)
+	*parameters = (*parameters)[:0]
+	handle, pathFound := s.Index.find(p, method, parameters)
+	parameterPool.Put(parameters)
+	return handle != nil || pathFound
+}
+
+// findCaseInsensitive will search below this node for a registered path that matches path without regard to the case
+// of static segments, and returns the path with the case of the registered segments.
+func (n *node) findCaseInsensitive(path string) (string, bool) {
+	b := &strings.Builder{}
+	if !n.findFold(path, b) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func (n *node) findFold(path string, b *strings.Builder) bool {
+	if path == "" && len(n.Methods) > 0 {
+		return true
+	}
+
+	length := b.Len()
+	reset := func() {
+		s := b.String()[:length]
+		b.Reset()
+		b.WriteString(s)
+	}
+
+	if path != "" {
+		for _, child := range n.Children {
+			if len(path) < len(child.Path) || !asciiEqualFold(path[:len(child.Path)], child.Path) {
+				continue
+			}
+			b.WriteString(child.Path)
+			if child.findFold(path[len(child.Path):], b) {
+				return true
+			}
+			reset()
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+		if value := path[0:end]; value != "" {
+			for _, child := range n.Parameters {
+				if child.Constraint != nil && !child.Constraint.match(value) {
+					continue
+				}
+				b.WriteString(value)
+				if child.findFold(path[end:], b) {
+					return true
+				}
+				reset()
+			}
+		}
+	}
+
+	if n.Wildcard != nil && len(n.Wildcard.Methods) > 0 {
+		b.WriteString(path)
+		return true
+	}
+
+	return false
+}
+
+func asciiEqualFold(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if toLowerASCII(a[i]) != toLowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func toLowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
+// redirectPath will redirect the request to p, preserving the query string
+func redirectPath(w http.ResponseWriter, req *http.Request, p string) {
+	code := http.StatusPermanentRedirect
+	if req.Method == "GET" || req.Method == "HEAD" {
+		code = http.StatusMovedPermanently
+	}
+	location := (&url.URL{Path: p, RawQuery: req.URL.RawQuery}).String()
+	w.Header().Set("Location", location)
+	w.WriteHeader(code)
+}
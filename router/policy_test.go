@@ -0,0 +1,55 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterPathPolicy(t *testing.T) {
+	t.Parallel()
+
+	handle := func(rw http.ResponseWriter, request router.Request) {}
+
+	server := router.New()
+	server.Handle("GET", "/users/all/", handle)
+	server.Handle("GET", "/users/:username", handle)
+	server.Handle("POST", "/Widgets/:id<int>/Cost", handle)
+	server.Handle("GET", "/files/*path", handle)
+
+	check := func(method, path string, expectedStatus int, expectedLocation string) {
+		resp := httptest.NewRecorder()
+		server.Handler().ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+		if resp.Code != expectedStatus {
+			t.Errorf("Unexpected status code for %s %s. Expected %d got %d", method, path, expectedStatus, resp.Code)
+		}
+		if location := resp.Header().Get("Location"); location != expectedLocation {
+			t.Errorf("Unexpected location for %s %s. Expected '%s' got '%s'", method, path, expectedLocation, location)
+		}
+	}
+
+	// Nothing is redirected without a policy
+	check("GET", "/users/all", 200, "")
+	check("GET", "/users/ian/", 404, "")
+	check("GET", "/users/./ian", 404, "")
+	check("POST", "/widgets/1/cost", 404, "")
+
+	server.SetPathPolicy(router.PathPolicy{
+		RedirectTrailingSlash: true,
+		CleanPath:             true,
+		CaseInsensitive:       true,
+	})
+
+	check("GET", "/users/all", 200, "")
+	check("GET", "/users/ian/?page=2", 301, "/users/ian?page=2")
+	check("GET", "/users//ian", 301, "/users/ian")
+	check("GET", "/files/../users/./ian", 301, "/users/ian")
+	check("GET", "/USERS/Ian", 301, "/users/Ian")
+	check("GET", "/USERS/ALL/", 301, "/users/all/")
+	check("POST", "/widgets/1/cost", 308, "/Widgets/1/Cost")
+	check("POST", "/widgets/one/cost", 404, "")
+	check("GET", "/nothing", 404, "")
+	check("PUT", "/users/ian", 405, "")
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// precompressedExtensions maps supported content codings to the file extension of their precompressed sibling
+var precompressedExtensions = map[string]string{
+	"br":   ".br",
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// validatePrecompressed will panic if any encoding is not supported
+func validatePrecompressed(encodings []string) {
+	for _, encoding := range encodings {
+		if _, ok := precompressedExtensions[encoding]; !ok {
+			panic(fmt.Sprintf("Unsupported precompressed encoding '%s'", encoding))
+		}
+	}
+}
+
+// parseAcceptEncoding returns the quality value for each content coding in the value of an Accept-Encoding header.
+// Codings with a malformed quality value are omitted.
+func parseAcceptEncoding(value string) map[string]float64 {
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(value, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		valid := true
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			quality = q
+		}
+		if valid {
+			accepted[coding] = quality
+		}
+	}
+	return accepted
+}
+
+// precompressed returns the content coding and name of the precompressed sibling of the named file that should be
+// served for the Accept-Encoding header, in the order of preference of the options. Returns false if the client does
+// not accept any of the encodings, if the named file is not a regular file, or if no sibling exists that is not hidden.
+func (o StaticOptions) precompressed(fsys fs.FS, name string, acceptEncoding string) (string, string, bool) {
+	if len(o.PrecompressedEncodings) == 0 || acceptEncoding == "" {
+		return "", "", false
+	}
+
+	// Only files that exist are served precompressed, so that every client gets the same response status
+	if info, err := fs.Stat(fsys, name); err != nil || info.IsDir() {
+		return "", "", false
+	}
+
+	for _, encoding := range o.PrecompressedEncodings {
+		if NegotiateContentEncoding(acceptEncoding, []string{encoding}) == "" {
+			continue
+		}
+
+		sibling := name + precompressedExtensions[encoding]
+		if o.isHidden(path.Base(sibling)) {
+			continue
+		}
+		if info, err := fs.Stat(fsys, sibling); err == nil && !info.IsDir() {
+			return encoding, sibling, true
+		}
+	}
+	return "", "", false
+}
+
+// NegotiateContentEncoding returns the first content coding in offered that is acceptable according to the value of an
+// Accept-Encoding header, or an empty string if none are. Offered codings should be in order of preference. A coding
+// is acceptable if it, or "*", is listed with a quality value greater than 0.
+func NegotiateContentEncoding(acceptEncoding string, offered []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := parseAcceptEncoding(acceptEncoding)
+	for _, encoding := range offered {
+		quality, listed := accepted[strings.ToLower(encoding)]
+		if !listed {
+			quality, listed = accepted["*"]
+		}
+		if listed && quality > 0 {
+			return encoding
+		}
+	}
+	return ""
+}
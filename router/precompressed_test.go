@@ -0,0 +1,147 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterPrecompressed(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"app.js":       {Data: []byte("plain")},
+		"app.js.br":    {Data: []byte("brotli")},
+		"app.js.gz":    {Data: []byte("gzip")},
+		"style.css":    {Data: []byte("css")},
+		"style.css.gz": {Data: []byte("css gzip")},
+		"orphan.js.gz": {Data: []byte("orphan")},
+	}
+
+	server := router.New()
+	server.ServeFS(fsys, "/default/")
+	options := router.DefaultStaticOptions()
+	options.PrecompressedEncodings = []string{"br", "zstd", "gzip"}
+	server.ServeFSWithOptions(fsys, "/static/", options)
+
+	type testCase struct {
+		Path           string
+		AcceptEncoding string
+		Range          string
+		Status         int
+		Body           string
+		Encoding       string
+	}
+
+	cases := []testCase{
+		{"/static/app.js", "gzip, deflate, br", "", 200, "brotli", "br"},
+		{"/static/app.js", "gzip", "", 200, "gzip", "gzip"},
+		{"/static/app.js", "br;q=0, gzip;q=0.5", "", 200, "gzip", "gzip"},
+		{"/static/app.js", "*", "", 200, "brotli", "br"},
+		{"/static/app.js", "*;q=0, identity", "", 200, "plain", ""},
+		{"/static/app.js", "", "", 200, "plain", ""},
+		{"/static/app.js", "br", "bytes=0-1", 206, "pl", ""},
+		{"/static/style.css", "br, gzip", "", 200, "css gzip", "gzip"},
+		{"/static/orphan.js", "gzip", "", 404, "", ""},
+		{"/default/app.js", "br", "", 200, "plain", ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.Path, nil)
+		if c.AcceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.AcceptEncoding)
+		}
+		if c.Range != "" {
+			req.Header.Set("Range", c.Range)
+		}
+		resp := httptest.NewRecorder()
+		server.Handler().ServeHTTP(resp, req)
+
+		if resp.Code != c.Status {
+			t.Errorf("Unexpected status for '%s' with '%s'. Expected %d got %d", c.Path, c.AcceptEncoding, c.Status, resp.Code)
+			continue
+		}
+		if c.Status == 404 {
+			continue
+		}
+		if body := resp.Body.String(); body != c.Body {
+			t.Errorf("Unexpected body for '%s' with '%s'. Expected '%s' got '%s'", c.Path, c.AcceptEncoding, c.Body, body)
+		}
+		if encoding := resp.Header().Get("Content-Encoding"); encoding != c.Encoding {
+			t.Errorf("Unexpected encoding for '%s' with '%s'. Expected '%s' got '%s'", c.Path, c.AcceptEncoding, c.Encoding, encoding)
+		}
+		if contentType := resp.Header().Get("Content-Type"); contentType != "text/javascript" && contentType != "text/css" {
+			t.Errorf("Unexpected content type for '%s' with '%s': '%s'", c.Path, c.AcceptEncoding, contentType)
+		}
+		vary := resp.Header().Get("Vary")
+		if (c.Path[0:8] == "/static/") != (vary == "Accept-Encoding") {
+			t.Errorf("Unexpected vary header for '%s': '%s'", c.Path, vary)
+		}
+	}
+}
+
+func TestRouterPrecompressedInvalid(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("No panic seen for unsupported precompressed encoding")
+		}
+	}()
+
+	options := router.DefaultStaticOptions()
+	options.PrecompressedEncodings = []string{"deflate"}
+	router.New().ServeFSWithOptions(fstest.MapFS{}, "/static/", options)
+}
+
+func TestRouterPrecompressedHidden(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"app.js":    {Data: []byte("plain")},
+		"app.js.br": {Data: []byte("brotli")},
+		"app.js.gz": {Data: []byte("gzip")},
+	}
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	options := router.DefaultStaticOptions()
+	options.PrecompressedEncodings = []string{"br", "gzip"}
+	options.HiddenFiles = []string{"*.br"}
+	server.ServeFSWithOptions(fsys, "/static/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	req, err := http.NewRequest("GET", "http://"+listenAddress+"/static/app.js", nil)
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Accept-Encoding", "br")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" || string(body) != "plain" {
+		t.Errorf("Hidden precompressed sibling served with encoding '%s'", resp.Header.Get("Content-Encoding"))
+	}
+
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" || string(body) != "gzip" {
+		t.Errorf("Unexpected response when a precompressed sibling is hidden. Encoding '%s' body '%s'", resp.Header.Get("Content-Encoding"), body)
+	}
+}
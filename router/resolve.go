@@ -0,0 +1,132 @@
+package router
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy describes how symbolic links are treated when serving files from a local directory
+type SymlinkPolicy int
+
+const (
+	// SymlinkAllowWithinRoot will follow symbolic links only if the file they resolve to is within the root directory
+	SymlinkAllowWithinRoot SymlinkPolicy = iota
+	// SymlinkDeny will not follow any symbolic links
+	SymlinkDeny
+	// SymlinkAllow will follow all symbolic links, even if they resolve to a file outside of the root directory
+	SymlinkAllow
+)
+
+// StaticSymlinkPolicy is the policy for symbolic links when serving files from a local directory with ServeFiles.
+// Files that are not permitted by the policy are treated as if they do not exist. Defaults to SymlinkAllowWithinRoot.
+// Use StaticOptions.SymlinkPolicy with ServeFilesWithOptions to set the policy for a single directory.
+var StaticSymlinkPolicy = SymlinkAllowWithinRoot
+
+// ServeDotFiles if the router should serve files or directories whose name begins with a '.', such as '.env' or '.git/'.
+// These are hidden from directory listings and treated as if they do not exist unless this is true. Use
+// StaticOptions.ServeDotFiles with ServeFilesWithOptions or ServeFSWithOptions to set this for a single mount.
+var ServeDotFiles = false
+
+// resolvePath returns the name of the file in a fs.FS for the request path, or false if the request path can not be
+// served. The returned name is always a valid path as defined by fs.ValidPath and never contains '..' segments.
+func resolvePath(requestPath string, options StaticOptions) (string, bool) {
+	if strings.ContainsAny(requestPath, "\\\x00") {
+		return "", false
+	}
+
+	name := path.Clean("/" + requestPath)[1:]
+	if name == "" {
+		return ".", true
+	}
+	if !fs.ValidPath(name) {
+		return "", false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if options.isHidden(segment) {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// rootFS is a fs.FS for a local directory that enforces a symlink policy
+type rootFS struct {
+	root   string
+	policy SymlinkPolicy
+}
+
+// resolve returns the local path for the named file according to the symlink policy
+func (r rootFS) resolve(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", fs.ErrNotExist
+	}
+	full := filepath.Join(r.root, filepath.FromSlash(name))
+
+	switch r.policy {
+	case SymlinkAllow:
+		return full, nil
+	case SymlinkDeny:
+		if name == "." {
+			return full, nil
+		}
+		current := r.root
+		for _, segment := range strings.Split(name, "/") {
+			current = filepath.Join(current, segment)
+			info, err := os.Lstat(current)
+			if err != nil {
+				return "", err
+			}
+			if info.Mode()&fs.ModeSymlink != 0 {
+				return "", fs.ErrNotExist
+			}
+		}
+		return full, nil
+	default:
+		root, err := filepath.EvalSymlinks(r.root)
+		if err != nil {
+			return "", err
+		}
+		resolved, err := filepath.EvalSymlinks(full)
+		if err != nil {
+			return "", err
+		}
+		if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+			return "", fs.ErrNotExist
+		}
+		return resolved, nil
+	}
+}
+
+func (r rootFS) Open(name string) (fs.File, error) {
+	full, err := r.resolve(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return os.Open(full)
+}
+
+// ReadDir returns the entries of the named directory, excluding any symbolic links not permitted by the policy
+func (r rootFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := r.resolve(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+
+	permitted := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if _, err := r.resolve(path.Join(name, entry.Name())); err != nil {
+				continue
+			}
+		}
+		permitted = append(permitted, entry)
+	}
+	return permitted, nil
+}
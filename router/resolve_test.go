@@ -0,0 +1,124 @@
+package router_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+const secretContent = "TOP-SECRET-CONTENT"
+
+// makeStaticRoot will make a root directory for serving files, with a secret file outside of the root and symbolic
+// links both within and outside of the root
+func makeStaticRoot(t testing.TB) string {
+	dir := t.TempDir()
+	root := path.Join(dir, "root")
+	os.Mkdir(root, 0755)
+	os.Mkdir(path.Join(root, "docs"), 0755)
+	os.WriteFile(path.Join(root, "public.txt"), []byte("public"), 0644)
+	os.WriteFile(path.Join(root, "docs", "readme.txt"), []byte("readme"), 0644)
+	os.WriteFile(path.Join(root, ".env"), []byte(secretContent), 0644)
+	os.Mkdir(path.Join(root, ".git"), 0755)
+	os.WriteFile(path.Join(root, ".git", "config"), []byte(secretContent), 0644)
+	os.WriteFile(path.Join(dir, "secret.txt"), []byte(secretContent), 0644)
+	if err := os.Symlink(path.Join(dir, "secret.txt"), path.Join(root, "outside.txt")); err != nil {
+		t.Skipf("Symbolic links not supported: %s", err.Error())
+	}
+	os.Symlink(dir, path.Join(root, "parent"))
+	os.Symlink(path.Join(root, "docs", "readme.txt"), path.Join(root, "inside.txt"))
+	return root
+}
+
+func staticRequest(server *router.Server, requestPath string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.URL.Path = requestPath
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRouterStaticResolve(t *testing.T) {
+	root := makeStaticRoot(t)
+
+	server := router.New()
+	server.ServeFiles(root, "/static/")
+
+	check := func(requestPath string, expectedStatus int) {
+		resp := staticRequest(server, requestPath)
+		if resp.Code != expectedStatus {
+			t.Errorf("Unexpected status code for '%s'. Expected %d got %d", requestPath, expectedStatus, resp.Code)
+		}
+		if strings.Contains(resp.Body.String(), secretContent) {
+			t.Errorf("Secret content served for '%s'", requestPath)
+		}
+	}
+
+	check("/static/public.txt", 200)
+	check("/static/docs/../public.txt", 200)
+	check("/static/inside.txt", 200)
+	check("/static/outside.txt", 404)
+	check("/static/parent/secret.txt", 404)
+	check("/static/parent/", 404)
+	check("/static/.env", 404)
+	check("/static/.git/config", 404)
+	check("/static/.git/", 404)
+	check("/static/docs\\..\\.env", 404)
+	check("/static/docs/readme.txt\x00.png", 404)
+
+	listing := staticRequest(server, "/static/").Body.String()
+	if strings.Contains(listing, ".env") || strings.Contains(listing, "outside.txt") || !strings.Contains(listing, "inside.txt") {
+		t.Errorf("Unexpected entries in directory listing")
+	}
+
+	router.StaticSymlinkPolicy = router.SymlinkDeny
+	check("/static/inside.txt", 404)
+	check("/static/public.txt", 200)
+	router.StaticSymlinkPolicy = router.SymlinkAllow
+	resp := staticRequest(server, "/static/outside.txt")
+	if resp.Code != 200 || resp.Body.String() != secretContent {
+		t.Errorf("Symbolic link outside of root not followed with SymlinkAllow")
+	}
+	router.StaticSymlinkPolicy = router.SymlinkAllowWithinRoot
+
+	router.ServeDotFiles = true
+	check("/static/.git/", 200)
+	router.ServeDotFiles = false
+}
+
+func FuzzRouterStaticResolve(f *testing.F) {
+	for _, seed := range []string{
+		"public.txt",
+		"../secret.txt",
+		"../../../../../../etc/passwd",
+		"%2e%2e/secret.txt",
+		"..\\secret.txt",
+		"docs/../../secret.txt",
+		"./outside.txt",
+		"parent/secret.txt",
+		"parent/root/.env",
+		".env",
+		"docs/./../.git/config",
+		"//secret.txt",
+		"docs/\x00/../secret.txt",
+	} {
+		f.Add(seed)
+	}
+
+	root := makeStaticRoot(f)
+	server := router.New()
+	server.ServeFiles(root, "/static/")
+
+	f.Fuzz(func(t *testing.T, requestPath string) {
+		resp := staticRequest(server, "/static/"+requestPath)
+		if resp.Code != 200 && resp.Code != 206 && resp.Code != 404 {
+			t.Errorf("Unexpected status code for '%s': %d", requestPath, resp.Code)
+		}
+		if strings.Contains(resp.Body.String(), secretContent) {
+			t.Errorf("Secret content served for '%s'", requestPath)
+		}
+	})
+}
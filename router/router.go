@@ -6,20 +6,19 @@ allows you to designate specific portions of the request path as a parameter, wh
 request itself.
 
 This package allows you modify the routing table ad-hoc, even while the server is running.
+
+Routes are stored in a compressed radix tree. Requests for paths without any parameters are matched without
+allocating any memory.
 */
 package router
 
 import (
 	"fmt"
+	"io/fs"
 	"net/http"
 	"runtime/debug"
 	"strings"
-)
-
-const (
-	pathKeyIndex     = "__router_index"
-	pathKeyParameter = "__router_parameter"
-	pathKeyWildcard  = "__router_wildcard"
+	"time"
 )
 
 func init() {
@@ -30,27 +29,35 @@ func init() {
 type Request struct {
 	// The underlaying HTTP request
 	HTTP *http.Request
-	// A map of any parameters from the router path mapped to their values from the request path
+	// A map of any parameters from the router path mapped to their values from the request path. Nil if the path has no
+	// parameters, so that matching static paths does not allocate. Reading from a nil map is safe, but handles must not
+	// write to it.
 	Parameters map[string]string
 }
 
 // Handle describes the signature for a handle of a path
 type Handle func(http.ResponseWriter, Request)
 
-type endpoint struct {
-	Methods   map[string]Handle
-	Children  map[string]endpoint
-	Parameter string
-}
+func (s *impl) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	req, handled := s.applyRules(w, req)
+	if handled {
+		return
+	}
 
-func newEndpoint() endpoint {
-	return endpoint{
-		Methods:  map[string]Handle{},
-		Children: map[string]endpoint{},
+	if host, hostParameter := s.hostFor(req.Host); host != nil {
+		if req, handled = host.applyRules(w, req); handled {
+			return
+		}
+		host.serve(w, req, hostParameter)
+		return
 	}
+
+	s.serve(w, req, parameterValue{})
 }
 
-func (s *impl) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+// serve will route the request using this routing table. If hostParameter has a name, it is included in the request
+// parameters.
+func (s *impl) serve(w http.ResponseWriter, req *http.Request, hostParameter parameterValue) {
 	s.Lock.RLock()
 	defer func() {
 		s.Lock.RUnlock()
@@ -65,145 +72,108 @@ func (s *impl) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
-	// Handle wildcard roots
-	if wildcardChild, exists := s.Index.Children[pathKeyWildcard]; exists {
-		handler, present := wildcardChild.Methods[req.Method]
-		if !present {
-			s.MethodNotAllowedHandle(w, req)
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	policy := s.pathPolicy()
+	if policy.CleanPath {
+		if cleaned := cleanPath(path); cleaned != path {
+			redirectPath(w, req, cleaned)
 			return
 		}
-		handler(w, Request{req, map[string]string{
-			wildcardChild.Parameter: req.URL.Path[1:], // trim the leading /
-		}})
-		return
 	}
 
-	parameters := map[string]string{}
+	if len(s.Mounts) > 0 {
+		if m := s.mountFor(path); m != nil {
+			m.Handler.ServeHTTP(w, stripPrefix(req, m.Prefix))
+			return
+		}
+	}
 
-	// If the request path ends in a slash, append the index path key
-	path := req.URL.Path
-	if path[len(path)-1] == '/' {
-		path += pathKeyIndex
+	parameters := parameterPool.Get().(*[]parameterValue)
+	*parameters = (*parameters)[:0]
+	if hostParameter.name != "" {
+		*parameters = append(*parameters, hostParameter)
 	}
-	segments := strings.Split(path[1:], "/")
-
-	// Start at the index:
-	// - if there is a matching child for the segment fetch that
-	// - if there is no matching segment, check for a wildcard
-	// - if there is no wildcard, check for a parameter
-	// once we've reached the last segment, find the handle for the request method
-	// - if no method found, check for other methods to return a 405
-	parent := s.Index
-	for i, segment := range segments {
-		child, exists := parent.Children[segment]
-
-		if !exists {
-			if wildcardChild, exists := parent.Children[pathKeyWildcard]; exists {
-				handler, present := wildcardChild.Methods[req.Method]
-				if !present {
-					s.MethodNotAllowedHandle(w, req)
-					return
-				}
-				value := strings.Join(segments[i:], "/")
-				if req.URL.Path[len(req.URL.Path)-1] == '/' {
-					value = value[0 : len(value)-len(pathKeyIndex)]
-				}
-				parameters[wildcardChild.Parameter] = value
-				handler(w, Request{req, parameters})
-				return
-			}
-			parameterChild, exists := parent.Children[pathKeyParameter]
-			if !exists {
-				s.NotFoundHandle(w, req)
+	handler, pathFound := s.Index.find(path, req.Method, parameters)
+	if handler == nil {
+		parameterPool.Put(parameters)
+		if !pathFound && (policy.RedirectTrailingSlash || policy.CaseInsensitive) {
+			if canonical := s.canonicalPath(path, req.Method, policy); canonical != "" && canonical != path {
+				redirectPath(w, req, canonical)
 				return
 			}
-			child = parameterChild
-			parameters[parameterChild.Parameter] = segment
+		}
+		if pathFound {
+			s.methodNotAllowed(w, req)
+			return
 		}
 
-		parent = &child
-
-		if i == len(segments)-1 { // last segment
-			handler, present := parent.Methods[req.Method]
-			if !present {
-				if len(parent.Methods) > 0 {
-					s.MethodNotAllowedHandle(w, req)
-					return
-				}
-
-				s.NotFoundHandle(w, req)
-				return
-			}
+		s.notFound(w, req)
+		return
+	}
 
-			handler(w, Request{req, parameters})
-			return
+	var values map[string]string
+	if len(*parameters) > 0 {
+		values = make(map[string]string, len(*parameters))
+		for _, parameter := range *parameters {
+			values[parameter.name] = parameter.value
 		}
 	}
+	parameterPool.Put(parameters)
 
-	// should never actually hit this
-	s.NotFoundHandle(w, req)
+	handler(w, Request{req, values})
 }
 
-func (s *Server) registerHandle(method, path string, handler Handle) {
+func (s *Server) registerHandle(method, path string, handler Handle, options RouteOptions) {
+	tokens := tokenizePath(path)
+
 	s.impl.Lock.Lock()
 	defer s.impl.Lock.Unlock()
 
-	if path[len(path)-1] == '/' {
-		path += pathKeyIndex
-	}
-	segments := strings.Split(path[1:], "/")
-
-	parent := s.impl.Index
-	for i, segment := range segments {
-		parameter := ""
-
-		// Since you can only have one unique parameter per segment, we don't
-		// have to worry about what the parameter name is.
-		if len(segment) > 1 {
-			if segment[0] == '*' {
-				parameter = segment[1:]
-				segment = pathKeyWildcard
-				i = len(segments) - 1
-			} else if segment[0] == ':' {
-				parameter = segment[1:]
-				segment = pathKeyParameter
-			}
-		}
-
-		if wc, exists := parent.Children[pathKeyWildcard]; exists {
-			if wc.Parameter != parameter {
-				panic("Path segment collides with wildcard")
-			}
-		}
-
-		child, exists := parent.Children[segment]
-		if !exists {
-			if segment == pathKeyWildcard && len(parent.Children) >= 1 {
-				panic("Path segment collides with wildcard")
-			}
-			if len(parent.Children) == 1 && parent.Children[pathKeyParameter].Parameter != "" {
-				panic("Path part '" + segment + "' collides with existing parameter :" + child.Parameter)
-			}
-
-			child = newEndpoint()
-			child.Parameter = parameter
-			parent.Children[segment] = child
+	if options.Name != "" {
+		if named, exists := s.impl.Names[options.Name]; exists && named.Path != path {
+			panic("Route name '" + options.Name + "' already registered for path " + named.Path)
 		}
+	}
 
-		parent = &child
-
-		if i == len(segments)-1 {
-			if _, exists := parent.Methods[method]; exists {
-				panic("Handle already registered for method and path")
-			}
-			parent.Methods[method] = handler
-			s.impl.log.PDebug("Register handle", map[string]interface{}{
-				"method": method,
-				"path":   path,
-			})
-			return
+	leaf := s.impl.Index.insert(tokens)
+	if _, exists := leaf.Methods[method]; exists {
+		panic("Handle already registered for method and path")
+	}
+	leaf.Methods[method] = &route{
+		Method:  method,
+		Path:    path,
+		Handle:  handler,
+		Options: options,
+		tokens:  tokens,
+	}
+	if options.Name != "" {
+		named := s.impl.Names[options.Name]
+		if named == nil {
+			named = &namedRoute{Path: path, tokens: tokens}
+			s.impl.Names[options.Name] = named
 		}
+		named.count++
 	}
+	s.impl.log.PDebug("Register handle", map[string]interface{}{
+		"method": method,
+		"path":   path,
+		"name":   options.Name,
+	})
+}
+
+// RouteOptions describes optional properties of a route
+type RouteOptions struct {
+	// Name is an optional name for the route, which can be used to build a URL for the route using Server.URLFor.
+	// Multiple methods for the same path may share a name, but a name can not be used for different paths.
+	Name string
+	// Kind is an optional description of the type of handle for the route, which is included in Server.Routes.
+	Kind string
+	// Summary is an optional description of any options for the handle, which is included in Server.Routes.
+	Summary map[string]string
 }
 
 // Handle registers a handler for an HTTP request of method to path.
@@ -230,23 +200,61 @@ func (s *Server) registerHandle(method, path string, handler Handle) {
 //	request path = "/proxy/some/multi/segmented/value"
 //	parameters   = { "url": "some/multi/segmented/value" }
 //
-// Parameter segments are exclusive, meaning you can not have a static segment at the same position as a
-// parameterized element. For example, these both will panic:
+// Static, parameter, and wildcard segments may share the same position. When matching a request, static segments
+// take priority over parameter segments, which take priority over wildcard segments. If a match fails deeper in the
+// path, the router backtracks and tries the next candidate. For example:
 //
-//	// This panics because /all occupies the same segment as the parameter :username
 //	server.Handle("GET", "/users/:username", ...)
 //	server.Handle("GET", "/users/all", ...)
+//	server.Handle("GET", "/users/*path", ...)
+//
+//	request path "/users/all"     matches "/users/all"
+//	request path "/users/ian"     matches "/users/:username"
+//	request path "/users/ian/foo" matches "/users/*path"
+//
+// A parameter may be constrained by adding a constraint in angle brackets after its name. Requests with a value that
+// does not satisfy the constraint will not match that segment, and the router will try other routes instead. The
+// constraint can be one of the built-in types: int, uint, hex, alpha, alnum, or uuid; otherwise it is treated as a
+// regular expression that must match the entire value. For example:
+//
+//	server.Handle("GET", "/widgets/:id<int>", ...)
+//	server.Handle("GET", "/widgets/:slug<[a-z0-9-]+>", ...)
+//	server.Handle("GET", "/widgets/:uuid<uuid>", ...)
 //
-//	// This panics because /user/id occupied the same segment as the wildcard parameter *param
-//	server.Handle("GET", "/users/*param", ...)
-//	server.Handle("GET", "/users/user/id", ...)
+// Constrained parameters are tried before unconstrained parameters at the same position. Parameters at the same
+// position with the same constraint must share the same name, and wildcards at the same position must also share the
+// same name. For example, this will panic:
+//
+//	server.Handle("GET", "/users/:username", ...)
+//	server.Handle("GET", "/users/:id/posts", ...)
 //
 // Paths that end with a slash are unique to those that don't. For example, these would be considred unique by the
 // router:
 //
 //	server.Handle("GET", "/users/all/", ...)
 //	server.Handle("GET", "/users/all", ...)
+//
+// By default, requests must match the registered path exactly. See SetPathPolicy to redirect requests for the other
+// slash form, unclean paths, or paths with a different case to the registered path.
+//
+// If no HEAD handle is registered for a path that has a GET handle, HEAD requests are answered by the GET handle. The
+// status and headers written by the handle are preserved, the body is discarded, and a Content-Length header is added
+// from the length of the discarded body if the handle did not set one itself.
 func (s *Server) Handle(method, path string, handler Handle) {
+	validateHandle(method, path)
+	s.registerHandle(method, path, handler, RouteOptions{})
+}
+
+// HandleWithOptions registers a handler for an HTTP request of method to path, with additional options for the route.
+// See Handle for details on the format of the method and path.
+//
+// Will panic if options.Name is already used by a route with a different path.
+func (s *Server) HandleWithOptions(method, path string, handler Handle, options RouteOptions) {
+	validateHandle(method, path)
+	s.registerHandle(method, path, handler, options)
+}
+
+func validateHandle(method, path string) {
 	methods := map[string]bool{
 		"CONNECT": true,
 		"DELETE":  true,
@@ -264,18 +272,20 @@ func (s *Server) Handle(method, path string, handler Handle) {
 	if path[0] != '/' {
 		panic("Path must start with /")
 	}
-	if strings.Contains(path, pathKeyIndex) || strings.Contains(path, pathKeyParameter) || strings.Contains(path, pathKeyWildcard) {
-		panic("Path contains reserved string sequence")
+	// These sequences were used internally by the segment map router and remain reserved for compatibility
+	for _, reserved := range []string{"__router_index", "__router_parameter", "__router_wildcard"} {
+		if strings.Contains(path, reserved) {
+			panic("Path contains reserved string sequence")
+		}
 	}
-
-	s.registerHandle(method, path, handler)
 }
 
 // RemoveHandle will remove any handler for the given method and path. If no handle exists, it does nothing.
 // If both method and path are * it removes everything from the routing table.
 //
-// Note that parameter names are not considered when removing a path. For example, you may register a path with
-// `/:username` and remove it with `/:something_else`.
+// Note that parameter names are not considered when removing a path, however constraints are. For example, you may
+// register a path with `/:username` and remove it with `/:something_else`, but `/:id<int>` must be removed with a
+// parameter that has the same `<int>` constraint.
 //
 // This may be called even while the server is listening and is threadsafe.
 func (s *Server) RemoveHandle(method, path string) {
@@ -284,8 +294,8 @@ func (s *Server) RemoveHandle(method, path string) {
 
 	if method == "*" && path == "*" {
 		s.impl.log.Debug("Removing all handles")
-		index := newEndpoint()
-		s.impl.Index = &index
+		s.impl.Index = newNode("")
+		s.impl.Names = map[string]*namedRoute{}
 		return
 	}
 
@@ -293,40 +303,19 @@ func (s *Server) RemoveHandle(method, path string) {
 		return
 	}
 
-	if path[len(path)-1] == '/' {
-		path += pathKeyIndex
-	}
-	segments := strings.Split(path[1:], "/")
-
-	parent := s.impl.Index
-	for i, segment := range segments {
-		if len(segment) > 1 {
-			if segment[0] == '*' {
-				segment = pathKeyWildcard
-			} else if segment[0] == ':' {
-				segment = pathKeyParameter
-			}
-		}
-
-		child, exists := parent.Children[segment]
-		if !exists {
-			return
-		}
-
-		if i == len(segments)-1 {
-			delete(child.Methods, method)
-			s.impl.log.PDebug("Remove handle", map[string]interface{}{
-				"method": method,
-				"path":   path,
-			})
-			if len(child.Methods) == 0 {
-				delete(parent.Children, segment)
+	removed := s.impl.Index.remove(tokenizePath(path), method)
+	if removed != nil && removed.Options.Name != "" {
+		if named := s.impl.Names[removed.Options.Name]; named != nil {
+			named.count--
+			if named.count <= 0 {
+				delete(s.impl.Names, removed.Options.Name)
 			}
-			return
-		} else {
-			parent = &child
 		}
 	}
+	s.impl.log.PDebug("Remove handle", map[string]interface{}{
+		"method": method,
+		"path":   path,
+	})
 }
 
 // ServeFiles registers a handler for all requests under urlRoot to serve any files matching the same path in
@@ -339,8 +328,8 @@ func (s *Server) RemoveHandle(method, path string) {
 //
 //	Request for '/static/image.jpg' would read file '/usr/share/www/image.jpg'
 //
-// Will panic if any handle is registered under urlRoot. Attempting to register a new handle under urlRoot after calling
-// ServeFiles will panic.
+// Other handles may be registered under urlRoot, and they will take priority over any files. Will panic if another
+// wildcard handle is registered at urlRoot.
 //
 // Caching will be enabled by default for all files served by this router. The mtime of the file will be used for the
 // Last-Modified date.
@@ -354,9 +343,83 @@ func (s *Server) RemoveHandle(method, path string) {
 // When a directory is requested, the router will look for an index file with the name from the IndexFileName variable.
 // If no file is found, a directory listing will automatically be generated. You can control this with the
 // GenerateDirectoryListing variable.
+//
+// The package variables apply to all directories served with ServeFiles. Use ServeFilesWithOptions to use different
+// options for each directory.
+//
+// Request paths are cleaned before they are resolved, and can never refer to a file outside of localRoot. Symbolic
+// links are only followed if they resolve to a file within localRoot, you can control this with the
+// StaticSymlinkPolicy variable. Files and directories whose name begins with a '.' are not served unless ServeDotFiles
+// is true. Requests for any file that can not be served receive the same response as a file that does not exist.
 func (s *Server) ServeFiles(localRoot string, urlRoot string) {
+	s.serveStatic(&staticMount{Root: localRoot}, urlRoot, map[string]string{
+		"directory": localRoot,
+	})
+}
+
+// ServeFilesWithOptions registers a handler for all requests under urlRoot to serve any files matching the same path
+// in a local filesystem directory localRoot, using the given options instead of the package variables. See ServeFiles
+// for details.
+//
+// For example:
+//
+//	options := router.DefaultStaticOptions()
+//	options.IndexFileNames = []string{"index.html", "index.htm"}
+//	options.CacheMaxAgeByExtension = map[string]time.Duration{".html": 0}
+//	server.ServeFilesWithOptions("/usr/share/www/", "/static/", options)
+func (s *Server) ServeFilesWithOptions(localRoot string, urlRoot string, options StaticOptions) {
+	s.serveStatic(&staticMount{Root: localRoot, Options: &options}, urlRoot, map[string]string{
+		"directory": localRoot,
+	})
+}
+
+// ServeFS registers a handler for all requests under urlRoot to serve any files matching the same path in the
+// filesystem fsys, such as an embed.FS. For example:
+//
+//	//go:embed assets
+//	var assets embed.FS
+//
+//	sub, _ := fs.Sub(assets, "assets")
+//	server.ServeFS(sub, "/static/")
+//
+//	Request for '/static/image.jpg' would read file 'assets/image.jpg' from the embedded filesystem
+//
+// Files are served exactly as they are by ServeFiles, including index files, directory listings, range requests, and
+// hiding files whose name begins with a '.', however StaticSymlinkPolicy does not apply to fsys. Range requests are
+// only supported if the files from fsys implement io.Seeker. Files that do not have a modification time, such as those
+// from an embed.FS, use the time that ServeFS was called for the Last-Modified date.
+//
+// Other handles may be registered under urlRoot, and they will take priority over any files. Will panic if another
+// wildcard handle is registered at urlRoot.
+func (s *Server) ServeFS(fsys fs.FS, urlRoot string) {
+	s.serveStatic(&staticMount{FS: fsys}, urlRoot, map[string]string{
+		"filesystem": fmt.Sprintf("%T", fsys),
+	})
+}
+
+// ServeFSWithOptions registers a handler for all requests under urlRoot to serve any files matching the same path in
+// the filesystem fsys, using the given options instead of the package variables. See ServeFS for details.
+func (s *Server) ServeFSWithOptions(fsys fs.FS, urlRoot string, options StaticOptions) {
+	s.serveStatic(&staticMount{FS: fsys, Options: &options}, urlRoot, map[string]string{
+		"filesystem": fmt.Sprintf("%T", fsys),
+	})
+}
+
+func (s *Server) serveStatic(mount *staticMount, urlRoot string, summary map[string]string) {
+	if mount.Options != nil {
+		validatePrecompressed(mount.Options.PrecompressedEncodings)
+		if fallback := mount.Options.FallbackFile; fallback != "" {
+			fallback = strings.TrimPrefix(fallback, "/")
+			if !fs.ValidPath(fallback) {
+				panic("Invalid static fallback file '" + mount.Options.FallbackFile + "'")
+			}
+			mount.Options.FallbackFile = fallback
+		}
+	}
+	// Truncated to the second as that is the precision of the Last-Modified header
+	mount.Registered = time.Now().Truncate(time.Second)
 	var handle Handle = func(rw http.ResponseWriter, r Request) {
-		s.impl.serveStatic(localRoot, r.Parameters["path"], rw, r.HTTP)
+		s.impl.serveStatic(mount, r.Parameters["path"], rw, r.HTTP)
 	}
 
 	if urlRoot[len(urlRoot)-1] != '/' {
@@ -364,6 +427,10 @@ func (s *Server) ServeFiles(localRoot string, urlRoot string) {
 	}
 	urlRoot += "*path"
 
-	s.Handle("GET", urlRoot, handle)
-	s.Handle("HEAD", urlRoot, handle)
+	options := RouteOptions{
+		Kind:    RouteKindStatic,
+		Summary: summary,
+	}
+	s.HandleWithOptions("GET", urlRoot, handle, options)
+	s.HandleWithOptions("HEAD", urlRoot, handle, options)
 }
@@ -1,6 +1,7 @@
 package router_test
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 
@@ -33,3 +34,17 @@ func ExampleServer_Serve() {
 	l, _ := net.Listen("tcp", "[::1]:8080")
 	server.Serve(l)
 }
+
+func ExampleServer_URLFor() {
+	server := router.New()
+	server.HandleWithOptions("GET", "/hello/:greeting", func(rw http.ResponseWriter, r router.Request) {
+		rw.Write([]byte("Hello, " + r.Parameters["greeting"]))
+	}, router.RouteOptions{Name: "hello"})
+
+	url, err := server.URLFor("hello", map[string]string{"greeting": "world"})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(url)
+	// Output: /hello/world
+}
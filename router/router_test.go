@@ -216,7 +216,7 @@ func TestRouterAddInvalidPathNoSlash(t *testing.T) {
 	t.Errorf("No panic seen when one expected for adding path without leading slash")
 }
 
-func TestRouterAddInvalidPathParameterClash(t *testing.T) {
+func TestRouterAddInvalidPathParameterNameClash(t *testing.T) {
 	t.Parallel()
 
 	defer func() {
@@ -227,11 +227,71 @@ func TestRouterAddInvalidPathParameterClash(t *testing.T) {
 	server.Handle("GET", "/one/:id/", func(rw http.ResponseWriter, request router.Request) {
 		//
 	})
-	server.Handle("GET", "/one/two/", func(rw http.ResponseWriter, request router.Request) {
+	server.Handle("GET", "/one/:name/two", func(rw http.ResponseWriter, request router.Request) {
 		//
 	})
 
-	t.Errorf("No panic seen when one expected for adding path that collides with parametrized path")
+	t.Errorf("No panic seen when one expected for adding path that collides with parameter name")
+}
+
+func TestRouterStaticParameterPrecedence(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/users/:username", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("parameter:" + request.Parameters["username"]))
+	})
+	server.Handle("GET", "/users/all", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("static"))
+	})
+	server.Handle("GET", "/users/*path", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("wildcard:" + request.Parameters["path"]))
+	})
+	server.Handle("GET", "/users/all/settings", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("static settings"))
+	})
+	server.Handle("GET", "/users/:username/posts", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("parameter posts:" + request.Parameters["username"]))
+	})
+	server.Handle("POST", "/users/all", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("static post"))
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	check := func(method, path, expected string) {
+		req, err := http.NewRequest(method, "http://"+listenAddress+path, nil)
+		if err != nil {
+			panic(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			panic(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Unexpected response for %s %s. Expected '%s' got '%s'", method, path, expected, data)
+		}
+	}
+
+	check("GET", "/users/all", "static")
+	check("GET", "/users/ian", "parameter:ian")
+	check("GET", "/users/ian/foo", "wildcard:ian/foo")
+	check("GET", "/users/all/settings", "static settings")
+	check("GET", "/users/all/posts", "parameter posts:all")
+	check("GET", "/users/ian/posts", "parameter posts:ian")
+	check("GET", "/users/ian/settings", "wildcard:ian/settings")
+	check("GET", "/users/", "wildcard:")
+	check("POST", "/users/all", "static post")
+	testURL(t, "POST", "http://"+listenAddress+"/users/ian", 405)
+	testURL(t, "GET", "http://"+listenAddress+"/other", 404)
 }
 
 func TestRouterAddInvalidDuplicateHandle(t *testing.T) {
@@ -484,40 +544,34 @@ func TestRouterWildcardMultipleHandle(t *testing.T) {
 	server.Handle("GET", "/users/user/:username", func(rw http.ResponseWriter, r router.Request) {})
 }
 
-func TestRouterWildcardStaticSegmentClashA(t *testing.T) {
-	t.Parallel()
-
-	defer func() {
-		recover()
-	}()
-
-	server := router.New()
-	server.Handle("GET", "/proxy/*url", func(rw http.ResponseWriter, request router.Request) {
-		//
-	})
-	server.Handle("GET", "/proxy/roxy", func(rw http.ResponseWriter, request router.Request) {
-		//
-	})
-
-	t.Errorf("No panic seen when one expected for adding path that collides with parametrized path")
-}
-
-func TestRouterWildcardStaticSegmentClashB(t *testing.T) {
+func TestRouterWildcardStaticSegment(t *testing.T) {
 	t.Parallel()
 
-	defer func() {
-		recover()
-	}()
+	listenAddress := getListenAddress()
 
 	server := router.New()
 	server.Handle("GET", "/proxy/roxy", func(rw http.ResponseWriter, request router.Request) {
-		//
+		rw.WriteHeader(201)
 	})
 	server.Handle("GET", "/proxy/*url", func(rw http.ResponseWriter, request router.Request) {
-		//
+		rw.WriteHeader(202)
 	})
+	server.Handle("GET", "/wildcard/*url", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(202)
+	})
+	server.Handle("GET", "/wildcard/roxy", func(rw http.ResponseWriter, request router.Request) {
+		rw.WriteHeader(201)
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
 
-	t.Errorf("No panic seen when one expected for adding path that collides with parametrized path")
+	testURL(t, "GET", "http://"+listenAddress+"/proxy/roxy", 201)
+	testURL(t, "GET", "http://"+listenAddress+"/proxy/roxy/foo", 202)
+	testURL(t, "GET", "http://"+listenAddress+"/proxy/foo", 202)
+	testURL(t, "GET", "http://"+listenAddress+"/wildcard/roxy", 201)
+	testURL(t, "GET", "http://"+listenAddress+"/wildcard/foo", 202)
 }
 
 func TestRouterWildcardParameterNameClash(t *testing.T) {
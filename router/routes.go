@@ -0,0 +1,181 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	_ "embed"
+)
+
+//go:embed route_table.html
+var routeTableTemplate string
+
+// Route kinds used by this package. Other packages may use their own kinds.
+const (
+	// RouteKindHandle is the kind for routes registered with Handle without a kind
+	RouteKindHandle = "Handle"
+	// RouteKindStatic is the kind for routes registered with ServeFiles
+	RouteKindStatic = "Static"
+	// RouteKindMount is the kind for handlers registered with Mount
+	RouteKindMount = "Mount"
+)
+
+// RouteInfo describes a route registered on the server
+type RouteInfo struct {
+	// The host pattern of the virtual host for the route, if any
+	Host string `json:"host,omitempty"`
+	// The HTTP method of the route, or "*" for handlers registered with Mount
+	Method string `json:"method"`
+	// The path of the route as it was registered, including any parameters
+	Path string `json:"path"`
+	// The name of the route, if any
+	Name string `json:"name,omitempty"`
+	// The kind of handle for the route
+	Kind string `json:"kind"`
+	// A summary of any options for the handle
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Routes returns information about every route registered on the server, sorted by host, path, and then method. Routes
+// for any virtual hosts created from the server are included.
+//
+// Routes may be called even while the server is listening and is threadsafe.
+func (s *Server) Routes() []RouteInfo {
+	routes := s.impl.routes()
+
+	s.impl.Lock.RLock()
+	hosts := make([]*impl, 0, len(s.impl.Hosts)+len(s.impl.WildcardHosts))
+	for _, host := range s.impl.Hosts {
+		hosts = append(hosts, host)
+	}
+	for _, host := range s.impl.WildcardHosts {
+		hosts = append(hosts, host.impl)
+	}
+	s.impl.Lock.RUnlock()
+	for _, host := range hosts {
+		routes = append(routes, host.routes()...)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Path == routes[j].Path {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
+}
+
+// routes returns information about every route in this routing table
+func (s *impl) routes() []RouteInfo {
+	s.Lock.RLock()
+	defer s.Lock.RUnlock()
+
+	routes := []RouteInfo{}
+	s.Index.walk(func(r *route) {
+		kind := r.Options.Kind
+		if kind == "" {
+			kind = RouteKindHandle
+		}
+		routes = append(routes, RouteInfo{
+			Host:    s.HostPattern,
+			Method:  r.Method,
+			Path:    r.Path,
+			Name:    r.Options.Name,
+			Kind:    kind,
+			Options: r.Options.Summary,
+		})
+	})
+	for _, m := range s.Mounts {
+		routes = append(routes, RouteInfo{
+			Host:   s.HostPattern,
+			Method: "*",
+			Path:   m.Prefix,
+			Kind:   RouteKindMount,
+		})
+	}
+	return routes
+}
+
+// walk will call fn for every route on this node and all nodes below it
+func (n *node) walk(fn func(r *route)) {
+	for _, r := range n.Methods {
+		fn(r)
+	}
+	for _, child := range n.Children {
+		child.walk(fn)
+	}
+	for _, child := range n.Parameters {
+		child.walk(fn)
+	}
+	if n.Wildcard != nil {
+		n.Wildcard.walk(fn)
+	}
+}
+
+type routeTableTemplateType struct {
+	Routes []RouteInfo
+}
+
+// RouteTableHandle returns a handle that will render the table of routes registered on the server. The table is
+// rendered as JSON if the request accepts "application/json" or includes the query "format=json", otherwise as HTML.
+//
+// The route table may reveal information about your application, you should take care to only expose it to operators.
+// For example:
+//
+//	server.Handle("GET", "/debug/routes", server.RouteTableHandle())
+func (s *Server) RouteTableHandle() Handle {
+	return func(w http.ResponseWriter, r Request) {
+		routes := s.Routes()
+
+		if strings.Contains(strings.ToLower(r.HTTP.Header.Get("Accept")), "application/json") || r.HTTP.URL.Query().Get("format") == "json" {
+			data, err := json.Marshal(routes)
+			if err != nil {
+				s.impl.log.PError("Error encoding route table", map[string]interface{}{
+					"error": err.Error(),
+				})
+				w.WriteHeader(500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
+			w.WriteHeader(200)
+			w.Write(data)
+			return
+		}
+
+		t, err := template.New("routes").Parse(routeTableTemplate)
+		if err != nil {
+			s.impl.log.PError("Error forming template for route table", map[string]interface{}{
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+
+		buf := &bytes.Buffer{}
+		if err := t.ExecuteTemplate(buf, "main", routeTableTemplateType{Routes: routes}); err != nil {
+			s.impl.log.PError("Error executing template for route table", map[string]interface{}{
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", buf.Len()))
+		w.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
+		w.WriteHeader(200)
+		io.Copy(w, buf)
+	}
+}
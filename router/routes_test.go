@@ -0,0 +1,103 @@
+package router_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterRoutes(t *testing.T) {
+	t.Parallel()
+
+	handle := func(rw http.ResponseWriter, request router.Request) {}
+
+	server := router.New()
+	server.Handle("POST", "/users/:username", handle)
+	server.Handle("GET", "/users/:username", handle)
+	server.HandleWithOptions("GET", "/users/all", handle, router.RouteOptions{
+		Name:    "all_users",
+		Kind:    "Custom",
+		Summary: map[string]string{"authenticated": "true"},
+	})
+	server.ServeFiles(t.TempDir(), "/static/")
+
+	routes := server.Routes()
+	if len(routes) != 5 {
+		t.Fatalf("Unexpected number of routes. Expected %d got %d", 5, len(routes))
+	}
+
+	expected := []router.RouteInfo{
+		{Method: "GET", Path: "/static/*path", Kind: router.RouteKindStatic},
+		{Method: "HEAD", Path: "/static/*path", Kind: router.RouteKindStatic},
+		{Method: "GET", Path: "/users/:username", Kind: router.RouteKindHandle},
+		{Method: "POST", Path: "/users/:username", Kind: router.RouteKindHandle},
+		{Method: "GET", Path: "/users/all", Kind: "Custom", Name: "all_users"},
+	}
+	for i, route := range routes {
+		if route.Method != expected[i].Method || route.Path != expected[i].Path || route.Kind != expected[i].Kind || route.Name != expected[i].Name {
+			t.Errorf("Unexpected route at index %d. Expected %+v got %+v", i, expected[i], route)
+		}
+	}
+	if routes[4].Options["authenticated"] != "true" {
+		t.Errorf("Missing options for route")
+	}
+	if routes[0].Options["directory"] == "" {
+		t.Errorf("Missing directory option for static route")
+	}
+
+	server.RemoveHandle("*", "*")
+	if len(server.Routes()) != 0 {
+		t.Errorf("Routes returned after removing all handles")
+	}
+}
+
+func TestRouterRouteTableHandle(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.Handle("GET", "/users/:username", func(rw http.ResponseWriter, request router.Request) {})
+	server.Handle("GET", "/debug/routes", server.RouteTableHandle())
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	req, err := http.NewRequest("GET", "http://"+listenAddress+"/debug/routes", nil)
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type '%s'", resp.Header.Get("Content-Type"))
+	}
+	routes := []router.RouteInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		t.Fatalf("Error decoding route table: %s", err.Error())
+	}
+	if len(routes) != 2 {
+		t.Errorf("Unexpected number of routes. Expected %d got %d", 2, len(routes))
+	}
+
+	resp, err = http.Get("http://" + listenAddress + "/debug/routes")
+	if err != nil {
+		panic(err)
+	}
+	if resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type '%s'", resp.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "/users/:username") {
+		t.Errorf("Route not included in HTML route table")
+	}
+}
@@ -0,0 +1,247 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Match types for rules
+const (
+	// RuleMatchExact matches requests where the path is exactly the pattern
+	RuleMatchExact = "exact"
+	// RuleMatchPrefix matches requests where the path is the pattern or begins with the pattern. The portion of the
+	// path after the pattern is appended to the target.
+	RuleMatchPrefix = "prefix"
+	// RuleMatchRegex matches requests where the entire path matches the regular expression in the pattern. The target
+	// may refer to capture groups of the expression, such as ${1} or ${name}.
+	RuleMatchRegex = "regex"
+)
+
+// Rule describes a redirect or rewrite rule that is evaluated before a request is routed
+type Rule struct {
+	// The host the rule applies to. Either an exact host name, such as "example.com", or a wildcard, such as
+	// "*.example.com". If empty the rule applies to all hosts.
+	Host string `json:"host,omitempty"`
+	// How the pattern is matched against the request path. One of RuleMatchExact, RuleMatchPrefix, or RuleMatchRegex.
+	// Defaults to RuleMatchExact.
+	Match string `json:"match,omitempty"`
+	// The pattern to match against the request path
+	Pattern string `json:"pattern"`
+	// The target for matching requests. For redirects this may be a path or an absolute URL, for rewrites this must be
+	// a path.
+	Target string `json:"target"`
+	// The HTTP status for redirects, one of 301, 302, 307, or 308. If 0 the request is rewritten internally to the
+	// target and routed as if the client had requested it.
+	Status int `json:"status,omitempty"`
+	// If true the query of the request is not added to the target
+	DiscardQuery bool `json:"discard_query,omitempty"`
+}
+
+type compiledRule struct {
+	Rule
+	regex *regexp.Regexp
+}
+
+// ruleSet is an immutable set of compiled rules
+type ruleSet struct {
+	Rules []compiledRule
+}
+
+// SetRules will replace all redirect and rewrite rules for the server. Rules are evaluated in order before the request
+// is routed, and only the first matching rule is applied. Rules set on a virtual host apply only to that host, and are
+// evaluated after the rules of the server it was created from. Rewritten requests are not evaluated against the rules
+// again.
+//
+// The query of the request is added to the target unless DiscardQuery is set. Returns an error if any rule is invalid,
+// in which case the existing rules are not changed. SetRules may be called even while the server is listening and is
+// threadsafe. Passing nil will remove all rules.
+func (s *Server) SetRules(rules []Rule) error {
+	set := &ruleSet{Rules: make([]compiledRule, len(rules))}
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return fmt.Errorf("rule %d: %s", i, err.Error())
+		}
+		set.Rules[i] = compiled
+	}
+
+	s.impl.Rules.Store(set)
+	s.impl.log.PDebug("Set rules", map[string]interface{}{
+		"count": len(rules),
+	})
+	return nil
+}
+
+// Rules returns a copy of the redirect and rewrite rules for the server
+func (s *Server) Rules() []Rule {
+	set := s.impl.Rules.Load()
+	if set == nil {
+		return []Rule{}
+	}
+	rules := make([]Rule, len(set.Rules))
+	for i, rule := range set.Rules {
+		rules[i] = rule.Rule
+	}
+	return rules
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	compiled := compiledRule{Rule: rule}
+	if compiled.Match == "" {
+		compiled.Match = RuleMatchExact
+	}
+	compiled.Host = strings.TrimSuffix(strings.ToLower(compiled.Host), ".")
+
+	if strings.ContainsAny(compiled.Host, "/: ") || (strings.HasPrefix(compiled.Host, "*") && !strings.HasPrefix(compiled.Host, "*.")) {
+		return compiled, fmt.Errorf("invalid host '%s'", rule.Host)
+	}
+
+	switch compiled.Status {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return compiled, fmt.Errorf("invalid redirect status %d", compiled.Status)
+	}
+
+	if compiled.Target == "" {
+		return compiled, fmt.Errorf("target is required")
+	}
+	if compiled.Status == 0 && compiled.Target[0] != '/' {
+		return compiled, fmt.Errorf("rewrite target must begin with a '/'")
+	}
+
+	switch compiled.Match {
+	case RuleMatchExact, RuleMatchPrefix:
+		if len(compiled.Pattern) == 0 || compiled.Pattern[0] != '/' {
+			return compiled, fmt.Errorf("pattern must begin with a '/'")
+		}
+	case RuleMatchRegex:
+		regex, err := regexp.Compile("^(?:" + compiled.Pattern + ")$")
+		if err != nil {
+			return compiled, fmt.Errorf("invalid pattern: %s", err.Error())
+		}
+		compiled.regex = regex
+	default:
+		return compiled, fmt.Errorf("unknown match type '%s'", compiled.Match)
+	}
+
+	return compiled, nil
+}
+
+// matchHost returns true if the rule applies to the normalized host
+func (r compiledRule) matchHost(host string) bool {
+	if r.Host == "" {
+		return true
+	}
+	if strings.HasPrefix(r.Host, "*.") {
+		suffix := r.Host[1:]
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return r.Host == host
+}
+
+// target returns the target for the path, or false if the rule does not match the path
+func (r compiledRule) target(path string) (string, bool) {
+	switch r.Match {
+	case RuleMatchExact:
+		if path != r.Pattern {
+			return "", false
+		}
+		return r.Target, true
+	case RuleMatchPrefix:
+		if !strings.HasPrefix(path, r.Pattern) {
+			return "", false
+		}
+		rest := path[len(r.Pattern):]
+		if rest != "" && !strings.HasSuffix(r.Pattern, "/") && rest[0] != '/' {
+			return "", false
+		}
+		if strings.HasSuffix(r.Target, "/") && strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+		}
+		return r.Target + rest, true
+	case RuleMatchRegex:
+		match := r.regex.FindStringSubmatchIndex(path)
+		if match == nil {
+			return "", false
+		}
+		return string(r.regex.ExpandString(nil, r.Target, path, match)), true
+	}
+	return "", false
+}
+
+// applyRules will evaluate the rules for this routing table against the request. If a redirect rule matched, a
+// response is written and handled is true. If a rewrite rule matched, the rewritten request is returned.
+func (s *impl) applyRules(w http.ResponseWriter, req *http.Request) (rewritten *http.Request, handled bool) {
+	set := s.Rules.Load()
+	if set == nil || len(set.Rules) == 0 {
+		return req, false
+	}
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	host := normalizeHost(req.Host)
+
+	for _, rule := range set.Rules {
+		if !rule.matchHost(host) {
+			continue
+		}
+		target, matched := rule.target(path)
+		if !matched {
+			continue
+		}
+
+		query := ""
+		if i := strings.IndexByte(target, '?'); i != -1 {
+			target, query = target[:i], target[i+1:]
+		}
+		if !rule.DiscardQuery && req.URL.RawQuery != "" {
+			if query != "" {
+				query += "&"
+			}
+			query += req.URL.RawQuery
+		}
+
+		if strings.HasPrefix(target, "/") {
+			// A substituted target starting with "//" or "/\" would be a protocol-relative redirect to another host
+			target = "/" + strings.TrimLeft(target, "/\\")
+		}
+
+		if rule.Status != 0 {
+			location := target
+			if target[0] == '/' {
+				location = (&url.URL{Path: target}).String()
+			}
+			if query != "" {
+				location += "?" + query
+			}
+			s.log.PDebug("Redirect request", map[string]interface{}{
+				"request_path": path,
+				"location":     location,
+				"status":       rule.Status,
+			})
+			w.Header().Set("Location", location)
+			w.WriteHeader(rule.Status)
+			return req, true
+		}
+
+		s.log.PDebug("Rewrite request", map[string]interface{}{
+			"request_path": path,
+			"target":       target,
+		})
+		r := new(http.Request)
+		*r = *req
+		r.URL = new(url.URL)
+		*r.URL = *req.URL
+		r.URL.Path = target
+		r.URL.RawPath = ""
+		r.URL.RawQuery = query
+		return r, false
+	}
+
+	return req, false
+}
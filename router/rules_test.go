@@ -0,0 +1,110 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterRules(t *testing.T) {
+	t.Parallel()
+
+	server := router.New()
+	server.Handle("GET", "/articles/:id", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("article " + request.Parameters["id"] + " " + request.HTTP.URL.RawQuery))
+	})
+	server.Host("blog.example.com").Handle("GET", "/", func(rw http.ResponseWriter, request router.Request) {
+		rw.Write([]byte("blog " + request.HTTP.URL.Path))
+	})
+
+	err := server.SetRules([]router.Rule{
+		{Pattern: "/old", Target: "/new", Status: 301},
+		{Match: router.RuleMatchPrefix, Pattern: "/docs", Target: "https://docs.example.com/", Status: 302, DiscardQuery: true},
+		{Match: router.RuleMatchRegex, Pattern: `/posts/(?P<id>\d+)\.html`, Target: "/articles/${id}?legacy=1"},
+		{Match: router.RuleMatchPrefix, Pattern: "/tmp/", Target: "/temporary/", Status: 307},
+		{Host: "*.example.com", Pattern: "/index.html", Target: "/"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error setting rules: %s", err.Error())
+	}
+
+	check := func(host, path string, expectedStatus int, expectedLocation, expectedBody string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = host
+		resp := httptest.NewRecorder()
+		server.Handler().ServeHTTP(resp, req)
+		if resp.Code != expectedStatus {
+			t.Errorf("Unexpected status code for %s%s. Expected %d got %d", host, path, expectedStatus, resp.Code)
+		}
+		if location := resp.Header().Get("Location"); location != expectedLocation {
+			t.Errorf("Unexpected location for %s%s. Expected '%s' got '%s'", host, path, expectedLocation, location)
+		}
+		if expectedBody != "" && resp.Body.String() != expectedBody {
+			t.Errorf("Unexpected body for %s%s. Expected '%s' got '%s'", host, path, expectedBody, resp.Body.String())
+		}
+	}
+
+	check("example.com", "/old?a=1", 301, "/new?a=1", "")
+	check("example.com", "/old/page", 404, "", "")
+	check("example.com", "/docs/api/index?a=1", 302, "https://docs.example.com/api/index", "")
+	check("example.com", "/docsify", 404, "", "")
+	check("example.com", "/tmp/a%20b", 307, "/temporary/a%20b", "")
+	check("example.com", "/posts/12.html?page=2", 200, "", "article 12 legacy=1&page=2")
+	check("example.com", "/posts/twelve.html", 404, "", "")
+	check("blog.example.com", "/index.html", 200, "", "blog /")
+	check("example.com", "/index.html", 404, "", "")
+
+	if len(server.Rules()) != 5 {
+		t.Errorf("Unexpected number of rules. Expected 5 got %d", len(server.Rules()))
+	}
+
+	if err := server.SetRules([]router.Rule{{Pattern: "/a", Target: "/b", Status: 200}}); err == nil {
+		t.Errorf("No error seen for invalid redirect status")
+	}
+	if err := server.SetRules([]router.Rule{{Match: router.RuleMatchRegex, Pattern: "/(", Target: "/b"}}); err == nil {
+		t.Errorf("No error seen for invalid regular expression")
+	}
+	if err := server.SetRules([]router.Rule{{Match: "glob", Pattern: "/a", Target: "/b"}}); err == nil {
+		t.Errorf("No error seen for unknown match type")
+	}
+	check("example.com", "/old", 301, "/new", "")
+
+	server.SetRules(nil)
+	check("example.com", "/old", 404, "", "")
+}
+
+func TestRouterRulesProtocolRelative(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	err := server.SetRules([]router.Rule{
+		{Match: router.RuleMatchRegex, Pattern: `/r/(?P<to>.*)`, Target: "/${to}", Status: 302},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error setting rules: %s", err.Error())
+	}
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	for _, requestPath := range []string{"/r//example.com/a", "/r/\\example.com/a", "/r////example.com/a"} {
+		resp, err := client.Get("http://" + listenAddress + requestPath)
+		if err != nil {
+			panic(err)
+		}
+		if location := resp.Header.Get("Location"); location != "/example.com/a" {
+			t.Errorf("Unexpected location for '%s'. Expected '/example.com/a' got '%s'", requestPath, location)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ecnepsnai/logtic"
@@ -19,22 +20,31 @@ type Server struct {
 
 type impl struct {
 	Lock                   *sync.RWMutex
-	Index                  *endpoint
+	Index                  *node
+	Names                  map[string]*namedRoute
+	Mounts                 []*mount
+	PathPolicy             *PathPolicy
+	Rules                  atomic.Pointer[ruleSet]
 	NotFoundHandle         func(http.ResponseWriter, *http.Request)
 	MethodNotAllowedHandle func(http.ResponseWriter, *http.Request)
+	Hosts                  map[string]*impl
+	WildcardHosts          []*wildcardHost
+	HostPattern            string
+	Parent                 *impl
 	log                    *logtic.Source
 }
 
 // New will initialize a new Server instance and return it. This does not start the server.
 func New() *Server {
-	index := newEndpoint()
 	log := logtic.Log.Connect("HTTP")
 	s := &Server{
 		impl: &impl{
 			Lock:                   &sync.RWMutex{},
-			Index:                  &index,
+			Index:                  newNode(""),
+			Names:                  map[string]*namedRoute{},
 			NotFoundHandle:         defaultNotFoundHandle,
 			MethodNotAllowedHandle: defaultMethodNotAllowedHandle,
+			Hosts:                  map[string]*impl{},
 			log:                    log,
 		},
 		httpServer: &http.Server{
@@ -73,6 +83,9 @@ func (s *Server) ListenAndServe(addr string) error {
 //
 // An error will only be returned if there was an error listening or the listener was abruptly closed.
 func (s *Server) Serve(listener net.Listener) error {
+	if s.httpServer == nil {
+		return errVirtualHostServe
+	}
 	s.impl.log.Debug("Serve on listener")
 	s.httpServer.Handler = s.impl
 	s.listener = &listener
@@ -93,7 +106,8 @@ func (s *Server) Stop() {
 
 // SetNotFoundHandle will set the handle called when a request that did not match any registered path comes in.
 //
-// A default handle is set when the server is created.
+// A default handle is set when the server is created. Virtual hosts use the handle of the server they were created
+// from unless they have their own.
 func (s *Server) SetNotFoundHandle(handle func(w http.ResponseWriter, r *http.Request)) {
 	s.impl.NotFoundHandle = handle
 }
@@ -101,7 +115,8 @@ func (s *Server) SetNotFoundHandle(handle func(w http.ResponseWriter, r *http.Re
 // SetMethodNotAllowedHandle will set the handle called when a request comes in for a known path but not the correct
 // method.
 //
-// A default handle is set when the server is created.
+// A default handle is set when the server is created. Virtual hosts use the handle of the server they were created
+// from unless they have their own.
 func (s *Server) SetMethodNotAllowedHandle(handle func(w http.ResponseWriter, r *http.Request)) {
 	s.impl.MethodNotAllowedHandle = handle
 }
@@ -1,65 +1,117 @@
 package router
 
 import (
-	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// CacheMaxAge the amount of time browsers may consider static content to be fresh.
-// Set this to 0 to not include a "Cache-Control" header for static requests.
-var CacheMaxAge time.Duration = 24 * time.Hour
+func (s *impl) serveStatic(mount *staticMount, url string, w http.ResponseWriter, req *http.Request) {
+	options := mount.options()
+	fsys := mount.filesystem(options)
 
-// IndexFileName is the name used when searching a directory for an index
-var IndexFileName = "index.html"
+	filePath, ok := resolvePath(url, options)
+	if !ok {
+		s.log.PInfo("Rejected static request path", map[string]interface{}{
+			"request_path": url,
+		})
+		s.notFound(w, req)
+		return
+	}
+	requestPath := url
 
-// GenerateDirectoryListing if the router should generate a directory listing for static directories that do not have
-// an index file (see also IndexFileName)
-var GenerateDirectoryListing = true
+	if url == "" || strings.HasSuffix(url, "/") {
+		directoryPath := ""
+		if filePath != "." {
+			directoryPath = filePath + "/"
+		}
+
+		if format := req.URL.Query().Get("archive"); format != "" && options.DirectoryArchives {
+			if info, err := fs.Stat(fsys, filePath); err == nil && info.IsDir() {
+				s.serveArchive(fsys, filePath, format, options, w, req)
+				return
+			}
+		}
 
-func (s *impl) serveStatic(dir, url string, w http.ResponseWriter, req *http.Request) {
-	requestPath := stripPath(url)
-	shouldRenderDirectoryListing := false
-	if requestPath == "" || strings.HasSuffix(requestPath, "/") {
 		// First check if an index file is found
-		if fileExists(path.Join(dir, requestPath+IndexFileName)) {
-			requestPath += IndexFileName
-		} else if fileExists(path.Join(dir, requestPath)) {
-			// If an index file is not found, check if the directory exists
-			shouldRenderDirectoryListing = true
+		indexFound := false
+		for _, indexFileName := range options.IndexFileNames {
+			if indexPath := path.Join(filePath, indexFileName); fileExists(fsys, indexPath) {
+				filePath = indexPath
+				indexFound = true
+				break
+			}
 		}
-	}
-	filePath := path.Join(dir, requestPath)
 
-	if shouldRenderDirectoryListing {
-		if !GenerateDirectoryListing {
-			s.NotFoundHandle(w, req)
+		// If an index file is not found, check if the directory exists
+		if !indexFound && fileExists(fsys, filePath) {
+			if !options.GenerateDirectoryListing {
+				s.staticNotFound(mount, options, fsys, requestPath, w, req)
+				return
+			}
+
+			s.makeDirectoryIndex(fsys, filePath, directoryPath, options, w, req)
 			return
 		}
+	}
+
+	s.serveStaticFile(mount, options, fsys, filePath, requestPath, false, w, req)
+}
 
-		s.makeDirectoryIndex(filePath, requestPath, w)
+// staticNotFound will serve the fallback file for the request if configured and applicable, otherwise the not found
+// handle is called
+func (s *impl) staticNotFound(mount *staticMount, options StaticOptions, fsys fs.FS, requestPath string, w http.ResponseWriter, req *http.Request) {
+	if !options.useFallback(requestPath, req) {
+		s.notFound(w, req)
 		return
 	}
 
+	s.log.PDebug("Serving static fallback file", map[string]interface{}{
+		"request_path": requestPath,
+		"file_path":    options.FallbackFile,
+	})
+	s.serveStaticFile(mount, options, fsys, options.FallbackFile, requestPath, true, w, req)
+}
+
+// serveStaticFile will serve the file at filePath. If fallback is true then the file is being served in place of a file
+// that does not exist.
+func (s *impl) serveStaticFile(mount *staticMount, options StaticOptions, fsys fs.FS, filePath string, requestPath string, fallback bool, w http.ResponseWriter, req *http.Request) {
+	notFound := func() {
+		if fallback {
+			s.notFound(w, req)
+			return
+		}
+		s.staticNotFound(mount, options, fsys, requestPath, w, req)
+	}
+
+	// Precompressed files are not used for range requests, as the ranges would apply to the encoded data
+	contentEncoding := ""
+	openPath := filePath
+	if req.Header.Get("Range") == "" {
+		if encoding, sibling, ok := options.precompressed(fsys, filePath, req.Header.Get("Accept-Encoding")); ok {
+			contentEncoding = encoding
+			openPath = sibling
+		}
+	}
+
 	s.log.PDebug("Serving static request", map[string]interface{}{
 		"request_path": requestPath,
-		"file_path":    filePath,
+		"file_path":    openPath,
 	})
 
-	f, err := os.OpenFile(filePath, os.O_RDONLY, os.ModePerm)
+	f, err := fsys.Open(openPath)
 	if err != nil {
 		s.log.PInfo("Static file not found", map[string]interface{}{
 			"request_path": requestPath,
 			"file_path":    filePath,
 		})
-		s.NotFoundHandle(w, req)
+		notFound()
 		return
 	}
 	defer f.Close()
@@ -71,36 +123,81 @@ func (s *impl) serveStatic(dir, url string, w http.ResponseWriter, req *http.Req
 			"file_path":    filePath,
 			"error":        err.Error(),
 		})
-		s.NotFoundHandle(w, req)
+		s.notFound(w, req)
+		return
+	}
+	if info.IsDir() {
+		s.log.PInfo("Static file is a directory", map[string]interface{}{
+			"request_path": requestPath,
+			"file_path":    filePath,
+		})
+		notFound()
 		return
 	}
 
-	sendBody := req.Method == "GET"
-	if modifiedSinceStr := req.Header.Get("If-Modified-Since"); modifiedSinceStr != "" {
-		modifiedSince, err := httpDateToTime(modifiedSinceStr)
-		if err != nil {
-			modifiedSince = time.Now()
-		}
+	// Files from some filesystems, such as embed.FS, do not have a modification time
+	modTime := info.ModTime()
+	if modTime.IsZero() {
+		modTime = mount.Registered
+	}
 
-		if info.ModTime().Sub(modifiedSince) < 0 {
-			sendBody = false
+	cacheControl := options.cacheControl(filePath)
+	if fallback {
+		cacheControl = options.fallbackCacheControl()
+	}
+	etag := mount.etag(options, f, openPath, info, modTime)
+	setValidators := func() {
+		options.setHeaders(w)
+		if len(options.PrecompressedEncodings) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
 		}
+		w.Header().Set("Last-Modified", timeToHTTPDate(modTime.UTC()))
+		w.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
 	}
 
-	if ranges := ParseRangeHeader(req.Header.Get("range")); len(ranges) > 0 && sendBody {
-		headers := map[string]string{
-			"Last-Modified": timeToHTTPDate(info.ModTime().UTC()),
+	switch CheckPreconditions(req, etag, modTime) {
+	case http.StatusNotModified:
+		setValidators()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	case http.StatusPreconditionFailed:
+		s.log.PDebug("Static request precondition failed", map[string]interface{}{
+			"request_path": requestPath,
+			"file_path":    filePath,
+		})
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	seeker, canSeek := f.(io.ReadSeeker)
+	if ranges := ParseRangeHeader(req.Header.Get("range")); len(ranges) > 0 && req.Method == "GET" && canSeek {
+		headers := map[string]string{}
+		for k, v := range options.Headers {
+			headers[k] = v
 		}
-		if CacheMaxAge > 0 {
-			headers["Cache-Control"] = fmt.Sprintf("max-age=%d; public", int(CacheMaxAge.Seconds()))
+		if cacheControl != "" {
+			headers["Cache-Control"] = cacheControl
+		}
+		if len(options.PrecompressedEncodings) > 0 {
+			headers["Vary"] = "Accept-Encoding"
 		}
 		err = ServeHTTPRange(ServeHTTPRangeOptions{
-			Headers:     headers,
-			Ranges:      ranges,
-			Reader:      f,
-			TotalLength: uint64(info.Size()),
-			MIMEType:    MimeGetter.GetMime(filePath),
-			Writer:      w,
+			Headers:         headers,
+			Ranges:          ranges,
+			Reader:          seeker,
+			TotalLength:     uint64(info.Size()),
+			MIMEType:        options.mime(filePath),
+			Writer:          w,
+			ETag:            etag,
+			LastModified:    modTime,
+			IfRange:         req.Header.Get("If-Range"),
+			omitCacheMaxAge: true,
 		})
 		if err != nil {
 			s.log.PError("Error serving ranged static file", map[string]interface{}{
@@ -112,15 +209,16 @@ func (s *impl) serveStatic(dir, url string, w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	if CacheMaxAge > 0 {
-		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d; public", int(CacheMaxAge.Seconds())))
+	setValidators()
+	if contentEncoding != "" {
+		w.Header().Set("Content-Encoding", contentEncoding)
 	}
-	w.Header().Set("Content-Type", MimeGetter.GetMime(filePath))
+	w.Header().Set("Content-Type", options.mime(filePath))
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
-	w.Header().Add("Last-Modified", timeToHTTPDate(info.ModTime().UTC()))
-	w.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
-	w.Header().Set("Accept-Ranges", "bytes")
-	if sendBody {
+	if canSeek {
+		w.Header().Set("Accept-Ranges", "bytes")
+	}
+	if req.Method == "GET" {
 		io.Copy(w, f)
 	} else {
 		w.WriteHeader(200)
@@ -144,10 +242,25 @@ type ServeHTTPRangeOptions struct {
 	MIMEType string
 	// The outgoing HTTP response writer
 	Writer http.ResponseWriter
+	// The entity tag of the data, if any. Included as the "ETag" header and compared against IfRange.
+	ETag string
+	// The modification time of the data, if any. Included as the "Last-Modified" header and compared against IfRange.
+	LastModified time.Time
+	// The value of the "If-Range" header from the HTTP request. If this does not match ETag or LastModified then the
+	// complete data is served instead of the requested ranges.
+	IfRange string
+
+	// If the CacheMaxAge variable should not be used for the "Cache-Control" header
+	omitCacheMaxAge bool
 }
 
-// ServeHTTPRange serve a HTTP range
+// ServeHTTPRange serve a HTTP range. If the If-Range header does not match the current entity tag or modification
+// time then the complete data is served with a 200 status instead.
 func ServeHTTPRange(options ServeHTTPRangeOptions) error {
+	if !ifRangeMatches(options.IfRange, options.ETag, options.LastModified) {
+		return serveHTTPRangeFull(options)
+	}
+
 	for i := 0; i < len(options.Ranges); i++ {
 		r := options.Ranges[i]
 		if r.Start >= int64(options.TotalLength) {
@@ -195,12 +308,44 @@ func handleRange(reader io.ReadSeeker, writer io.Writer, r ByteRange) error {
 	return nil
 }
 
+// setHeaders will add the validator and custom headers and cookies to the response
+func (options ServeHTTPRangeOptions) setHeaders() {
+	if options.ETag != "" {
+		options.Writer.Header().Set("ETag", options.ETag)
+	}
+	if !options.LastModified.IsZero() {
+		options.Writer.Header().Set("Last-Modified", timeToHTTPDate(options.LastModified.UTC()))
+	}
+	for k, v := range options.Headers {
+		options.Writer.Header().Set(k, v)
+	}
+	for _, cookie := range options.Cookies {
+		http.SetCookie(options.Writer, &cookie)
+	}
+}
+
+func serveHTTPRangeFull(options ServeHTTPRangeOptions) error {
+	options.setHeaders()
+	options.Writer.Header().Set("Content-Type", options.MIMEType)
+	options.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", options.TotalLength))
+	options.Writer.Header().Set("Accept-Ranges", "bytes")
+	options.Writer.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
+	options.Writer.WriteHeader(200)
+
+	if _, err := options.Reader.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(options.Writer, options.Reader)
+	return err
+}
+
 func serveHTTPRangeSingle(options ServeHTTPRangeOptions) error {
 	r := options.Ranges[0]
 
-	if CacheMaxAge > 0 {
+	if CacheMaxAge > 0 && !options.omitCacheMaxAge {
 		options.Writer.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d; public", int(CacheMaxAge.Seconds())))
 	}
+	options.setHeaders()
 	options.Writer.Header().Set("Content-Type", options.MIMEType)
 	options.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", r.Length(options.TotalLength)))
 	options.Writer.Header().Set("Content-Range", r.ContentRangeValue(options.TotalLength))
@@ -212,13 +357,8 @@ func serveHTTPRangeSingle(options ServeHTTPRangeOptions) error {
 
 func serveHTTPRangeMulti(options ServeHTTPRangeOptions) error {
 	mp := multipart.NewWriter(options.Writer)
+	options.setHeaders()
 	options.Writer.Header().Set("Content-Type", fmt.Sprintf("multipart/byteranges; boundary=%s", mp.Boundary()))
-	for k, v := range options.Headers {
-		options.Writer.Header().Add(k, v)
-	}
-	for _, cookie := range options.Cookies {
-		http.SetCookie(options.Writer, &cookie)
-	}
 	options.Writer.Header().Set("Date", timeToHTTPDate(time.Now().UTC()))
 	options.Writer.WriteHeader(206)
 
@@ -242,24 +382,12 @@ func serveHTTPRangeMulti(options ServeHTTPRangeOptions) error {
 
 const httpDateLayout = "Mon, 02 Jan 2006 15:04:05 GMT"
 
-func httpDateToTime(date string) (time.Time, error) {
-	return time.Parse(httpDateLayout, date)
-}
-
 func timeToHTTPDate(date time.Time) string {
 	return date.Format(httpDateLayout)
 }
 
-func stripPath(inS string) (outS string) {
-	// fast strip ../ from all paths
-	in := []byte(inS)
-	out := bytes.ReplaceAll(in, []byte{0x2e, 0x2e, 0x2f}, []byte{})
-	outS = string(out)
-	return
-}
-
-func fileExists(filePath string) bool {
-	_, err := os.Stat(filePath)
+func fileExists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
 	return err == nil
 }
 
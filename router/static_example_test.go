@@ -1,6 +1,8 @@
 package router_test
 
 import (
+	"testing/fstest"
+
 	"github.com/ecnepsnai/web/router"
 )
 
@@ -15,3 +17,20 @@ func ExampleServer_ServeFiles() {
 	// For example:
 	// HTTP GET "/assets/index.html" will read file "/usr/share/http/index.html"
 }
+
+func ExampleServer_ServeFS() {
+	server := router.New()
+
+	// Files can be served from any fs.FS, such as an embed.FS:
+	//
+	//	//go:embed assets
+	//	var assets embed.FS
+	assets := fstest.MapFS{
+		"index.html": {Data: []byte("<p>Hello</p>")},
+	}
+
+	server.ServeFS(assets, "/assets/")
+	// Now any HTTP GET or HEAD requests to /assets/ will read files from the filesystem.
+	// For example:
+	// HTTP GET "/assets/index.html" will read file "index.html"
+}
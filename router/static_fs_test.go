@@ -0,0 +1,155 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+var staticFSModTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var staticFSFiles = fstest.MapFS{
+	"index.html":       {Data: []byte("<p>index</p>")},
+	"css/site.css":     {Data: []byte("body{}"), ModTime: staticFSModTime},
+	"docs/readme.txt":  {Data: []byte("0123456789")},
+	"docs/old/old.txt": {Data: []byte("old")},
+}
+
+func TestRouterServeFS(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFS(staticFSFiles, "/static/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testStaticRequest(t, "GET", "http://"+listenAddress+"/static/", 200, "text/html")
+	testStaticRequest(t, "GET", "http://"+listenAddress+"/static/css/site.css", 200, "text/css")
+	testStaticRequest(t, "GET", "http://"+listenAddress+"/static/docs/", 200, "text/html; charset=utf-8")
+	testStaticRequest(t, "GET", "http://"+listenAddress+"/static/docs/readme.txt", 200, "text/plain")
+	testURL(t, "GET", "http://"+listenAddress+"/static/docs", 404)
+	testURL(t, "GET", "http://"+listenAddress+"/static/nothing.txt", 404)
+
+	resp, err := http.Get("http://" + listenAddress + "/static/")
+	if err != nil {
+		panic(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "<p>index</p>" {
+		t.Errorf("Unexpected body for index. Expected '<p>index</p>' got '%s'", body)
+	}
+}
+
+func TestRouterServeFSTraversal(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFS(staticFSFiles, "/static/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	req, err := http.NewRequest("GET", "http://"+listenAddress+"/", nil)
+	if err != nil {
+		panic(err)
+	}
+	req.URL.Opaque = "/static/../../docs/readme.txt"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "0123456789" {
+		t.Errorf("Unexpected response for path outside of the file system: %d '%s'", resp.StatusCode, body)
+	}
+}
+
+func TestRouterServeFSLastModified(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+	start := time.Now().Truncate(time.Second)
+
+	server := router.New()
+	server.ServeFS(staticFSFiles, "/static/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, err := http.Get("http://" + listenAddress + "/static/css/site.css")
+	if err != nil {
+		panic(err)
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != staticFSModTime.Format(http.TimeFormat) {
+		t.Errorf("Unexpected Last-Modified for file with modification time: '%s'", lastModified)
+	}
+
+	resp, err = http.Get("http://" + listenAddress + "/static/docs/readme.txt")
+	if err != nil {
+		panic(err)
+	}
+	lastModified, err := time.Parse(http.TimeFormat, resp.Header.Get("Last-Modified"))
+	if err != nil || lastModified.Before(start) {
+		t.Errorf("Unexpected Last-Modified for file without modification time: '%s'", resp.Header.Get("Last-Modified"))
+	}
+}
+
+func TestRouterServeFSRange(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFS(staticFSFiles, "/static/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	req, err := http.NewRequest("GET", "http://"+listenAddress+"/static/docs/readme.txt", nil)
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Range", "bytes=2-4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 206 || string(body) != "234" {
+		t.Errorf("Unexpected range response. Expected 206 '234' got %d '%s'", resp.StatusCode, body)
+	}
+}
+
+func TestRouterServeFSHead(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFS(staticFSFiles, "/static/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, err := http.Head("http://" + listenAddress + "/static/docs/readme.txt")
+	if err != nil {
+		panic(err)
+	}
+	if resp.StatusCode != 200 || resp.ContentLength != 10 {
+		t.Errorf("Unexpected HEAD response. Got %d with length %d", resp.StatusCode, resp.ContentLength)
+	}
+}
@@ -0,0 +1,191 @@
+package router
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheMaxAge the amount of time browsers may consider static content to be fresh.
+// Set this to 0 to not include a "Cache-Control" header for static requests.
+//
+// Deprecated: Use StaticOptions.CacheMaxAge with ServeFilesWithOptions or ServeFSWithOptions. This is only used as the
+// default for ServeFiles, ServeFS, and ServeHTTPRange.
+var CacheMaxAge time.Duration = 24 * time.Hour
+
+// IndexFileName is the name used when searching a directory for an index
+//
+// Deprecated: Use StaticOptions.IndexFileNames with ServeFilesWithOptions or ServeFSWithOptions. This is only used as
+// the default for ServeFiles and ServeFS.
+var IndexFileName = "index.html"
+
+// GenerateDirectoryListing if the router should generate a directory listing for static directories that do not have
+// an index file (see also IndexFileName)
+//
+// Deprecated: Use StaticOptions.GenerateDirectoryListing with ServeFilesWithOptions or ServeFSWithOptions. This is only
+// used as the default for ServeFiles and ServeFS.
+var GenerateDirectoryListing = true
+
+// StaticOptions describes options for serving static files. Each call to ServeFilesWithOptions or ServeFSWithOptions
+// may use different options. Use DefaultStaticOptions to start with the default options.
+type StaticOptions struct {
+	// The amount of time browsers may consider static content to be fresh. Set this to 0 to not include a
+	// "Cache-Control" header.
+	CacheMaxAge time.Duration
+	// The amount of time browsers may consider static content to be fresh for files with specific extensions, which
+	// takes priority over CacheMaxAge. Keys are the extension including the leading '.', such as ".html", and are not
+	// case sensitive. Set a value to 0 to not include a "Cache-Control" header for files with that extension.
+	CacheMaxAgeByExtension map[string]time.Duration
+	// The names used when searching a directory for an index, in order of priority
+	IndexFileNames []string
+	// If a directory listing should be generated for directories that do not have an index file
+	GenerateDirectoryListing bool
+	// An optional template used for directory listings instead of the default listing. The template is executed with a
+	// DirectoryListing. Requests that accept "application/json" always receive the listing as JSON.
+	DirectoryListingTemplate *template.Template
+	// If a directory may be downloaded as an archive by requesting it with the "archive" query parameter set to "zip"
+	// or "tar.gz", such as "/files/photos/?archive=zip". The archive is streamed as it is created and excludes hidden
+	// files.
+	DirectoryArchives bool
+	// The maximum total size in bytes of the files in a directory archive. Requests for archives of directories larger
+	// than this are rejected with a 403 status. Set to 0 for no limit.
+	MaxArchiveSize int64
+	// The implementation used to determine the value of the "Content-Type" header. If nil the MimeGetter variable is
+	// used.
+	MimeGetter IMime
+	// Additional headers to include in every response for a file or directory listing
+	Headers map[string]string
+	// If files or directories whose name begins with a '.', such as '.env' or '.git/', should be served
+	ServeDotFiles bool
+	// Patterns for the names of files or directories that should not be served, in addition to those beginning with a
+	// '.'. Patterns use the syntax of path.Match and are matched against the name of every file and directory in the
+	// request path, for example "*.bak". Hidden files are excluded from directory listings and treated as if they do not
+	// exist.
+	HiddenFiles []string
+	// The policy for symbolic links when serving files from a local directory. Has no effect for ServeFSWithOptions.
+	SymlinkPolicy SymlinkPolicy
+	// How the "ETag" header is generated for files. Defaults to ETagWeak.
+	ETag ETagMode
+	// The content codings of precompressed files that may be served, in order of preference. Supported codings are
+	// "br", "gzip", and "zstd", which are read from a sibling file with the extension ".br", ".gz", or ".zst". For
+	// example, with []string{"br", "gzip"} a request for "app.js" from a client that accepts brotli would be served
+	// "app.js.br" if it exists. The "Content-Type" is always that of the original file. Range requests are always
+	// served from the original file.
+	PrecompressedEncodings []string
+	// The name of a file, relative to the root, to serve in place of any file that does not exist, such as
+	// "index.html" for a single-page application. The fallback file is only served for requests whose path does not
+	// have an extension or that accept "text/html", so that missing assets such as "app.js" still receive a 404.
+	FallbackFile string
+	// The value of the "Cache-Control" header when the fallback file is served, since the fallback file is not
+	// identified by the request path it should not be cached for long. Defaults to "no-cache".
+	FallbackCacheControl string
+}
+
+// DefaultStaticOptions returns the options used by ServeFiles and ServeFS, which are based on the CacheMaxAge,
+// IndexFileName, GenerateDirectoryListing, MimeGetter, ServeDotFiles, and StaticSymlinkPolicy variables.
+func DefaultStaticOptions() StaticOptions {
+	return StaticOptions{
+		CacheMaxAge:              CacheMaxAge,
+		IndexFileNames:           []string{IndexFileName},
+		GenerateDirectoryListing: GenerateDirectoryListing,
+		MimeGetter:               MimeGetter,
+		ServeDotFiles:            ServeDotFiles,
+		SymlinkPolicy:            StaticSymlinkPolicy,
+	}
+}
+
+// cacheControl returns the value for the "Cache-Control" header for the named file, or an empty string if none should
+// be included
+func (o StaticOptions) cacheControl(name string) string {
+	maxAge := o.CacheMaxAge
+	extension := path.Ext(name)
+	for key, extensionMaxAge := range o.CacheMaxAgeByExtension {
+		if strings.EqualFold(key, extension) {
+			maxAge = extensionMaxAge
+			break
+		}
+	}
+	if maxAge <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("max-age=%d; public", int(maxAge.Seconds()))
+}
+
+// mime returns the value for the "Content-Type" header for the named file
+func (o StaticOptions) mime(name string) string {
+	if o.MimeGetter != nil {
+		return o.MimeGetter.GetMime(name)
+	}
+	return MimeGetter.GetMime(name)
+}
+
+// isHidden returns true if a file or directory with the given name should not be served
+func (o StaticOptions) isHidden(name string) bool {
+	if !o.ServeDotFiles && strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, pattern := range o.HiddenFiles {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// useFallback returns true if the fallback file should be served for a request path that does not exist
+func (o StaticOptions) useFallback(requestPath string, req *http.Request) bool {
+	if o.FallbackFile == "" {
+		return false
+	}
+	return path.Ext(requestPath) == "" || strings.Contains(req.Header.Get("Accept"), "text/html")
+}
+
+// fallbackCacheControl returns the value for the "Cache-Control" header when the fallback file is served
+func (o StaticOptions) fallbackCacheControl() string {
+	if o.FallbackCacheControl == "" {
+		return "no-cache"
+	}
+	return o.FallbackCacheControl
+}
+
+// setHeaders will add the custom headers to the response
+func (o StaticOptions) setHeaders(w http.ResponseWriter) {
+	for k, v := range o.Headers {
+		w.Header().Set(k, v)
+	}
+}
+
+// staticMount describes a directory or filesystem registered with ServeFiles or ServeFS
+type staticMount struct {
+	// The local directory, if this is a directory mount
+	Root string
+	// The filesystem, if this is not a directory mount
+	FS fs.FS
+	// The options for the mount, if nil the default options are used for each request
+	Options *StaticOptions
+	// The time the mount was registered, used for files without a modification time
+	Registered time.Time
+	// Strong entity tags for files, keyed by file name
+	ETags sync.Map
+}
+
+// options returns the options for the mount
+func (m *staticMount) options() StaticOptions {
+	if m.Options == nil {
+		return DefaultStaticOptions()
+	}
+	return *m.Options
+}
+
+// filesystem returns the filesystem for the mount
+func (m *staticMount) filesystem(options StaticOptions) fs.FS {
+	if m.FS != nil {
+		return m.FS
+	}
+	return rootFS{root: m.Root, policy: options.SymlinkPolicy}
+}
@@ -0,0 +1,121 @@
+package router_test
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+type testMimeGetter struct{}
+
+func (testMimeGetter) GetMime(filePath string) string {
+	return "application/x-test"
+}
+
+func makeStaticOptionsDir(t *testing.T) string {
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "default.htm"), []byte("index"), 0644)
+	os.WriteFile(path.Join(dir, "app.js"), []byte("js"), 0644)
+	os.WriteFile(path.Join(dir, "page.html"), []byte("html"), 0644)
+	os.WriteFile(path.Join(dir, "backup.bak"), []byte("backup"), 0644)
+	os.WriteFile(path.Join(dir, ".env"), []byte("env"), 0644)
+	os.Mkdir(path.Join(dir, "empty"), 0755)
+	return dir
+}
+
+func testStaticHeaders(t *testing.T, url string, requestHeaders map[string]string, expectedStatus int, expectedHeaders map[string]string) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		panic(err)
+	}
+	for k, v := range requestHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != expectedStatus {
+		t.Errorf("Unexpected status code for URL '%s'. Expected %d got %d", url, expectedStatus, resp.StatusCode)
+	}
+	for k, v := range expectedHeaders {
+		if actual := resp.Header.Get(k); actual != v {
+			t.Errorf("Unexpected header %s for URL '%s'. Expected '%s' got '%s'", k, url, v, actual)
+		}
+	}
+}
+
+func TestRouterServeFilesDefaultOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := makeStaticOptionsDir(t)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeFiles(dir, "/default/")
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testStaticHeaders(t, "http://"+listenAddress+"/default/app.js", nil, 200, map[string]string{"Content-Type": "text/javascript", "X-Content-Type-Options": ""})
+	testStaticHeaders(t, "http://"+listenAddress+"/default/backup.bak", nil, 200, nil)
+	testStaticHeaders(t, "http://"+listenAddress+"/default/.env", nil, 404, nil)
+	testStaticHeaders(t, "http://"+listenAddress+"/default/empty/", nil, 200, map[string]string{"Content-Type": "text/html; charset=utf-8"})
+}
+
+func TestRouterServeFilesWithOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := makeStaticOptionsDir(t)
+	listenAddress := getListenAddress()
+
+	options := router.DefaultStaticOptions()
+	options.CacheMaxAge = time.Hour
+	options.CacheMaxAgeByExtension = map[string]time.Duration{".HTML": 0, ".js": 2 * time.Hour}
+	options.IndexFileNames = []string{"index.html", "default.htm"}
+	options.GenerateDirectoryListing = false
+	options.MimeGetter = testMimeGetter{}
+	options.Headers = map[string]string{"X-Content-Type-Options": "nosniff"}
+	options.ServeDotFiles = true
+	options.HiddenFiles = []string{"*.bak"}
+
+	server := router.New()
+	server.ServeFilesWithOptions(dir, "/custom/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/", nil, 200, map[string]string{"Content-Type": "application/x-test"})
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/app.js", nil, 200, map[string]string{"Cache-Control": "max-age=7200; public", "X-Content-Type-Options": "nosniff"})
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/page.html", nil, 200, map[string]string{"Cache-Control": ""})
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/backup.bak", nil, 404, nil)
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/.env", nil, 200, nil)
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/empty/", nil, 404, nil)
+}
+
+func TestRouterServeFilesWithOptionsRange(t *testing.T) {
+	t.Parallel()
+
+	dir := makeStaticOptionsDir(t)
+	listenAddress := getListenAddress()
+
+	options := router.DefaultStaticOptions()
+	options.CacheMaxAgeByExtension = map[string]time.Duration{".js": 2 * time.Hour}
+	options.Headers = map[string]string{"X-Content-Type-Options": "nosniff"}
+
+	server := router.New()
+	server.ServeFilesWithOptions(dir, "/custom/", options)
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testStaticHeaders(t, "http://"+listenAddress+"/custom/app.js", map[string]string{"Range": "bytes=0-0"}, 206, map[string]string{"Cache-Control": "max-age=7200; public", "X-Content-Type-Options": "nosniff"})
+}
@@ -794,3 +794,42 @@ func TestRangeEndIndexOutOfRange(t *testing.T) {
 		t.Fatalf("invalid data returned")
 	}
 }
+
+func TestRangeMultipleNoCacheControl(t *testing.T) {
+	t.Parallel()
+
+	listenAddress := getListenAddress()
+	server := router.New()
+	server.Handle("GET", "/data", func(rw http.ResponseWriter, request router.Request) {
+		router.ServeHTTPRange(router.ServeHTTPRangeOptions{
+			Ranges:      router.ParseRangeHeader(request.HTTP.Header.Get("Range")),
+			Reader:      bytes.NewReader(sampleData),
+			TotalLength: uint64(len(sampleData)),
+			MIMEType:    "text/plain",
+			Writer:      rw,
+		})
+	})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s/data", listenAddress), nil)
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Add("Range", "bytes=0-10,20-30")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+
+	if resp.StatusCode != 206 {
+		t.Fatalf("unexpected HTTP status code. Expected %d got %d", 206, resp.StatusCode)
+	}
+
+	if value := resp.Header.Get("Cache-Control"); value != "" {
+		t.Fatalf("unexpected Cache-Control header on multiple range response '%s'", value)
+	}
+}
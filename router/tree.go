@@ -0,0 +1,341 @@
+package router
+
+import (
+	"strings"
+	"sync"
+)
+
+// node is a node in the compressed radix tree used for routing. Static nodes store a prefix of the request path and
+// are indexed by the first byte of that prefix. Parameter and wildcard nodes consume a segment of the request path, or
+// the rest of the request path, respectively, and continue matching with their own static children.
+type node struct {
+	Path       string
+	Indices    string
+	Children   []*node
+	Parameters []*node
+	Wildcard   *node
+	Name       string
+	Constraint *parameterConstraint
+	Methods    map[string]*route
+}
+
+// route describes a handle registered for a method and path
+type route struct {
+	Method  string
+	Path    string
+	Handle  Handle
+	Options RouteOptions
+	tokens  []routeToken
+}
+
+func newNode(path string) *node {
+	return &node{
+		Path:    path,
+		Methods: map[string]*route{},
+	}
+}
+
+func (n *node) isEmpty() bool {
+	return len(n.Methods) == 0 && len(n.Children) == 0 && len(n.Parameters) == 0 && n.Wildcard == nil
+}
+
+type tokenKind int
+
+const (
+	tokenStatic tokenKind = iota
+	tokenParameter
+	tokenWildcard
+)
+
+// routeToken describes a portion of a registered path
+type routeToken struct {
+	Kind tokenKind
+	// For static tokens this is the static text, otherwise it is the parameter name
+	Value      string
+	Constraint *parameterConstraint
+}
+
+// tokenizePath will split a registered path into static, parameter, and wildcard tokens. Any segments after a wildcard
+// are ignored.
+func tokenizePath(path string) []routeToken {
+	tokens := []routeToken{}
+	static := "/"
+	for i, segment := range splitPath(path[1:]) {
+		if i > 0 {
+			static += "/"
+		}
+
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			if static != "" {
+				tokens = append(tokens, routeToken{Kind: tokenStatic, Value: static})
+				static = ""
+			}
+			if segment[0] == '*' {
+				if strings.ContainsRune(segment, '<') {
+					panic("Wildcard segment '" + segment + "' can not have a constraint")
+				}
+				return append(tokens, routeToken{Kind: tokenWildcard, Value: segment[1:]})
+			}
+			token := routeToken{Kind: tokenParameter}
+			name, constraint := parseParameter(segment[1:])
+			token.Value = name
+			if constraint != "" {
+				token.Constraint = newParameterConstraint(constraint)
+			}
+			tokens = append(tokens, token)
+			continue
+		}
+
+		static += segment
+	}
+	if static != "" {
+		tokens = append(tokens, routeToken{Kind: tokenStatic, Value: static})
+	}
+	return tokens
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// staticChild returns the static child whose prefix begins with c
+func (n *node) staticChild(c byte) (int, *node) {
+	for i := 0; i < len(n.Indices); i++ {
+		if n.Indices[i] == c {
+			return i, n.Children[i]
+		}
+	}
+	return -1, nil
+}
+
+// insertStatic will add the static path below this node, splitting any existing nodes as needed, and returns the node
+// where path ends.
+func (n *node) insertStatic(path string) *node {
+	parent := n
+	for path != "" {
+		_, child := parent.staticChild(path[0])
+		if child == nil {
+			child = newNode(path)
+			parent.Indices += string(path[0])
+			parent.Children = append(parent.Children, child)
+			return child
+		}
+
+		common := longestCommonPrefix(child.Path, path)
+		if common < len(child.Path) {
+			// Split the child so that it ends at the common prefix, with the remainder becoming its only child
+			rest := &node{
+				Path:       child.Path[common:],
+				Indices:    child.Indices,
+				Children:   child.Children,
+				Parameters: child.Parameters,
+				Wildcard:   child.Wildcard,
+				Methods:    child.Methods,
+			}
+			child.Path = child.Path[:common]
+			child.Indices = string(rest.Path[0])
+			child.Children = []*node{rest}
+			child.Parameters = nil
+			child.Wildcard = nil
+			child.Methods = map[string]*route{}
+		}
+
+		parent = child
+		path = path[common:]
+	}
+	return parent
+}
+
+// parameterChild returns the parameter child with the same constraint, if any
+func (n *node) parameterChild(constraint *parameterConstraint) (int, *node) {
+	for i, child := range n.Parameters {
+		if child.Constraint.sourceEqual(constraint) {
+			return i, child
+		}
+	}
+	return -1, nil
+}
+
+// addParameterChild adds a new parameter child. Constrained parameters are placed before unconstrained ones so that
+// they are tried first.
+func (n *node) addParameterChild(child *node) {
+	if child.Constraint == nil {
+		n.Parameters = append(n.Parameters, child)
+		return
+	}
+
+	i := len(n.Parameters)
+	if i > 0 && n.Parameters[i-1].Constraint == nil {
+		i--
+	}
+	n.Parameters = append(n.Parameters, nil)
+	copy(n.Parameters[i+1:], n.Parameters[i:])
+	n.Parameters[i] = child
+}
+
+// insert will add the tokens below this node and return the node for the final token. Will panic if a parameter or
+// wildcard collides with an existing one of a different name.
+func (n *node) insert(tokens []routeToken) *node {
+	current := n
+	for _, token := range tokens {
+		switch token.Kind {
+		case tokenStatic:
+			current = current.insertStatic(token.Value)
+		case tokenParameter:
+			_, child := current.parameterChild(token.Constraint)
+			if child == nil {
+				child = newNode("")
+				child.Name = token.Value
+				child.Constraint = token.Constraint
+				current.addParameterChild(child)
+			} else if child.Name != token.Value {
+				panic("Path segment ':" + token.Value + "' collides with existing parameter :" + child.Name)
+			}
+			current = child
+		case tokenWildcard:
+			if current.Wildcard == nil {
+				current.Wildcard = newNode("")
+				current.Wildcard.Name = token.Value
+			} else if current.Wildcard.Name != token.Value {
+				panic("Path segment '*" + token.Value + "' collides with existing wildcard *" + current.Wildcard.Name)
+			}
+			current = current.Wildcard
+		}
+	}
+	return current
+}
+
+// find will search below this node for a handle matching the remaining request path and method. Static children are
+// searched first, then parameter children with a constraint that matches the segment, then parameter children without
+// a constraint, then the wildcard child. If a deeper search fails, the next candidate is tried. Values for any
+// parameters are appended to parameters.
+//
+// If no handle was found, pathFound will be true if a handle for any other method exists for the path.
+func (n *node) find(path string, method string, parameters *[]parameterValue) (handle Handle, pathFound bool) {
+	if path == "" {
+		if handle, present := n.handleForMethod(method); present {
+			return handle, true
+		}
+		pathFound = len(n.Methods) > 0
+	} else {
+		if _, child := n.staticChild(path[0]); child != nil && strings.HasPrefix(path, child.Path) {
+			handle, found := child.find(path[len(child.Path):], method, parameters)
+			if handle != nil {
+				return handle, true
+			}
+			pathFound = pathFound || found
+		}
+
+		if len(n.Parameters) > 0 {
+			end := strings.IndexByte(path, '/')
+			if end == -1 {
+				end = len(path)
+			}
+			value := path[0:end]
+			if value != "" {
+				for _, child := range n.Parameters {
+					if child.Constraint != nil && !child.Constraint.match(value) {
+						continue
+					}
+					*parameters = append(*parameters, parameterValue{child.Name, value})
+					handle, found := child.find(path[end:], method, parameters)
+					if handle != nil {
+						return handle, true
+					}
+					*parameters = (*parameters)[:len(*parameters)-1]
+					pathFound = pathFound || found
+				}
+			}
+		}
+	}
+
+	if n.Wildcard != nil {
+		if handle, present := n.Wildcard.handleForMethod(method); present {
+			*parameters = append(*parameters, parameterValue{n.Wildcard.Name, path})
+			return handle, true
+		}
+		pathFound = pathFound || len(n.Wildcard.Methods) > 0
+	}
+
+	return nil, pathFound
+}
+
+// remove will remove the route for method at the node matching the tokens, pruning any nodes that are left empty and
+// merging static nodes that are left with a single child. Returns the removed route, if any.
+func (n *node) remove(tokens []routeToken, method string) (removed *route) {
+	if len(tokens) == 0 {
+		removed = n.Methods[method]
+		delete(n.Methods, method)
+		return removed
+	}
+
+	token := tokens[0]
+	switch token.Kind {
+	case tokenStatic:
+		removed = n.removeStatic(token.Value, tokens[1:], method)
+	case tokenParameter:
+		i, child := n.parameterChild(token.Constraint)
+		if child == nil {
+			return nil
+		}
+		removed = child.remove(tokens[1:], method)
+		if child.isEmpty() {
+			n.Parameters = append(n.Parameters[:i], n.Parameters[i+1:]...)
+		}
+	case tokenWildcard:
+		if n.Wildcard == nil {
+			return nil
+		}
+		removed = n.Wildcard.remove(nil, method)
+		if n.Wildcard.isEmpty() {
+			n.Wildcard = nil
+		}
+	}
+	return removed
+}
+
+func (n *node) removeStatic(path string, tokens []routeToken, method string) (removed *route) {
+	i, child := n.staticChild(path[0])
+	if child == nil || !strings.HasPrefix(path, child.Path) {
+		return nil
+	}
+
+	if len(path) == len(child.Path) {
+		removed = child.remove(tokens, method)
+	} else {
+		removed = child.removeStatic(path[len(child.Path):], tokens, method)
+	}
+
+	if child.isEmpty() {
+		n.Indices = n.Indices[:i] + n.Indices[i+1:]
+		n.Children = append(n.Children[:i], n.Children[i+1:]...)
+		return removed
+	}
+
+	if len(child.Methods) == 0 && len(child.Parameters) == 0 && child.Wildcard == nil && len(child.Children) == 1 {
+		only := child.Children[0]
+		child.Path += only.Path
+		child.Indices = only.Indices
+		child.Children = only.Children
+		child.Parameters = only.Parameters
+		child.Wildcard = only.Wildcard
+		child.Methods = only.Methods
+	}
+	return removed
+}
+
+type parameterValue struct {
+	name  string
+	value string
+}
+
+var parameterPool = sync.Pool{
+	New: func() interface{} {
+		parameters := make([]parameterValue, 0, 8)
+		return &parameters
+	},
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// namedRoute describes the path for a route name, and how many handles are registered with that name
+type namedRoute struct {
+	Path   string
+	tokens []routeToken
+	count  int
+}
+
+// URLFor will build the path for the route registered with the given name, substituting any parameter or wildcard
+// segments with the matching value from parameters. Keys in parameters do not include the ':' or '*'.
+//
+// Parameter values are escaped so that they occupy a single segment. Wildcard values may contain slashes, but each
+// segment of the value is escaped. Returns an error if no route has the given name, if a value for a parameter is
+// missing or empty, or if a value does not satisfy the constraint of the parameter.
+//
+// For example:
+//
+//	server.HandleWithOptions("GET", "/users/:username/files/*path", handle, router.RouteOptions{Name: "user_file"})
+//	server.URLFor("user_file", map[string]string{"username": "ian", "path": "photos/cat.jpg"})
+//	// "/users/ian/files/photos/cat.jpg"
+func (s *Server) URLFor(name string, parameters map[string]string) (string, error) {
+	s.impl.Lock.RLock()
+	named, exists := s.impl.Names[name]
+	s.impl.Lock.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("no route named '%s'", name)
+	}
+
+	return buildURL(named.tokens, parameters)
+}
+
+func buildURL(tokens []routeToken, parameters map[string]string) (string, error) {
+	b := &strings.Builder{}
+	for _, token := range tokens {
+		switch token.Kind {
+		case tokenStatic:
+			b.WriteString(escapePath(token.Value))
+		case tokenParameter:
+			value, present := parameters[token.Value]
+			if !present || value == "" {
+				return "", fmt.Errorf("missing value for parameter '%s'", token.Value)
+			}
+			if token.Constraint != nil && !token.Constraint.match(value) {
+				return "", fmt.Errorf("value for parameter '%s' does not satisfy constraint <%s>", token.Value, token.Constraint.Source)
+			}
+			b.WriteString(url.PathEscape(value))
+		case tokenWildcard:
+			value, present := parameters[token.Value]
+			if !present {
+				return "", fmt.Errorf("missing value for wildcard '%s'", token.Value)
+			}
+			b.WriteString(escapePath(strings.TrimPrefix(value, "/")))
+		}
+	}
+	return b.String(), nil
+}
+
+// escapePath will escape each segment of path while preserving slashes
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
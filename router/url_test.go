@@ -0,0 +1,74 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+func TestRouterURLFor(t *testing.T) {
+	t.Parallel()
+
+	handle := func(rw http.ResponseWriter, request router.Request) {}
+
+	server := router.New()
+	server.HandleWithOptions("GET", "/", handle, router.RouteOptions{Name: "index"})
+	server.HandleWithOptions("GET", "/users/:username", handle, router.RouteOptions{Name: "user"})
+	server.HandleWithOptions("HEAD", "/users/:username", handle, router.RouteOptions{Name: "user"})
+	server.HandleWithOptions("GET", "/widgets/:id<int>/", handle, router.RouteOptions{Name: "widget"})
+	server.HandleWithOptions("GET", "/users/:username/files/*path", handle, router.RouteOptions{Name: "user_file"})
+	server.Handle("GET", "/unnamed", handle)
+
+	check := func(name string, parameters map[string]string, expected string) {
+		url, err := server.URLFor(name, parameters)
+		if err != nil {
+			t.Errorf("Unexpected error building URL for '%s': %s", name, err.Error())
+			return
+		}
+		if url != expected {
+			t.Errorf("Unexpected URL for '%s'. Expected '%s' got '%s'", name, expected, url)
+		}
+	}
+
+	check("index", nil, "/")
+	check("user", map[string]string{"username": "ian"}, "/users/ian")
+	check("user", map[string]string{"username": "a/b c"}, "/users/a%2Fb%20c")
+	check("widget", map[string]string{"id": "12"}, "/widgets/12/")
+	check("user_file", map[string]string{"username": "ian", "path": "photos/my cat.jpg"}, "/users/ian/files/photos/my%20cat.jpg")
+	check("user_file", map[string]string{"username": "ian", "path": ""}, "/users/ian/files/")
+
+	if _, err := server.URLFor("unnamed", nil); err == nil {
+		t.Errorf("No error seen for unknown route name")
+	}
+	if _, err := server.URLFor("user", nil); err == nil {
+		t.Errorf("No error seen for missing parameter")
+	}
+	if _, err := server.URLFor("widget", map[string]string{"id": "twelve"}); err == nil {
+		t.Errorf("No error seen for parameter that does not satisfy constraint")
+	}
+	if _, err := server.URLFor("user_file", map[string]string{"username": "ian"}); err == nil {
+		t.Errorf("No error seen for missing wildcard")
+	}
+
+	server.RemoveHandle("GET", "/users/:username")
+	check("user", map[string]string{"username": "ian"}, "/users/ian")
+	server.RemoveHandle("HEAD", "/users/:username")
+	if _, err := server.URLFor("user", map[string]string{"username": "ian"}); err == nil {
+		t.Errorf("No error seen for removed route")
+	}
+}
+
+func TestRouterURLForNameClash(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		recover()
+	}()
+
+	server := router.New()
+	server.HandleWithOptions("GET", "/one", func(rw http.ResponseWriter, request router.Request) {}, router.RouteOptions{Name: "one"})
+	server.HandleWithOptions("GET", "/two", func(rw http.ResponseWriter, request router.Request) {}, router.RouteOptions{Name: "one"})
+
+	t.Errorf("No panic seen when one expected for adding duplicate route name")
+}
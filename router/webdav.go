@@ -0,0 +1,799 @@
+package router
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WebDAVOptions describes options for a WebDAV handler
+type WebDAVOptions struct {
+	// If true then only the OPTIONS, GET, HEAD, and PROPFIND methods are allowed
+	ReadOnly bool
+	// Options used for GET and HEAD requests, hidden files, and symbolic links. Hidden files are excluded from PROPFIND
+	// responses and can not be created, copied, or moved. Use DefaultStaticOptions to start with the default options.
+	Static StaticOptions
+	// The maximum amount of time a lock may be held before it must be refreshed. Defaults to 1 hour.
+	MaxLockTimeout time.Duration
+}
+
+func (o WebDAVOptions) maxLockTimeout() time.Duration {
+	if o.MaxLockTimeout <= 0 {
+		return time.Hour
+	}
+	return o.MaxLockTimeout
+}
+
+// webDAV is a http.Handler implementing WebDAV (RFC 4918) class 1 and 2 for a local directory
+type webDAV struct {
+	// The URL path the handler is mounted at, without a trailing slash
+	Prefix string
+	// The local directory
+	Root string
+	// The options for the handler
+	Options WebDAVOptions
+	// The static mount used for GET and HEAD requests
+	Mount *staticMount
+	// The router the handler belongs to, used for logging and error handles
+	Server *impl
+	// Locks held on files within the directory
+	Locks *davLocks
+}
+
+// ServeWebDAV mounts a WebDAV handler at urlRoot for the local directory localRoot, allowing the directory to be
+// browsed and modified with WebDAV clients, such as the file managers of most operating systems. Locks are held in
+// memory and are lost when the server is restarted.
+//
+// Request paths are resolved exactly as they are for ServeFilesWithOptions, using the symbolic link and hidden file
+// options from options.Static. See Mount for details on how the handler is mounted.
+func (s *Server) ServeWebDAV(localRoot string, urlRoot string, options WebDAVOptions) {
+	s.Mount(urlRoot, s.WebDAVHandler(localRoot, urlRoot, options))
+}
+
+// WebDAVHandler returns a WebDAV handler for the local directory localRoot, which must be mounted at urlRoot. Use this
+// instead of ServeWebDAV to wrap the handler, for example to add authentication.
+func (s *Server) WebDAVHandler(localRoot string, urlRoot string, options WebDAVOptions) http.Handler {
+	validatePrecompressed(options.Static.PrecompressedEncodings)
+	static := options.Static
+	return &webDAV{
+		Prefix:  strings.TrimRight(urlRoot, "/"),
+		Root:    localRoot,
+		Options: options,
+		Mount:   &staticMount{Root: localRoot, Options: &static, Registered: time.Now().Truncate(time.Second)},
+		Server:  s.impl,
+		Locks:   newDAVLocks(),
+	}
+}
+
+func (d *webDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestPath := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case "OPTIONS":
+		d.handleOptions(w, r)
+		return
+	case "GET", "HEAD":
+		d.Server.serveStatic(d.Mount, requestPath, w, r)
+		return
+	}
+
+	name, ok := resolvePath(requestPath, d.Options.Static)
+	if !ok {
+		d.Server.log.PInfo("Rejected WebDAV request path", map[string]interface{}{
+			"method":       r.Method,
+			"request_path": requestPath,
+		})
+		if r.Method == "PROPFIND" {
+			d.Server.notFound(w, r)
+		} else {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return
+	}
+
+	if r.Method == "PROPFIND" {
+		d.handlePropfind(w, r, name)
+		return
+	}
+
+	if d.Options.ReadOnly {
+		w.Header().Set("Allow", d.allow())
+		d.Server.methodNotAllowed(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "MKCOL":
+		d.handleMkcol(w, r, name)
+	case "PUT":
+		d.handlePut(w, r, name)
+	case "DELETE":
+		d.handleDelete(w, r, name)
+	case "COPY", "MOVE":
+		d.handleCopyMove(w, r, name)
+	case "LOCK":
+		d.handleLock(w, r, name)
+	case "UNLOCK":
+		d.handleUnlock(w, r, name)
+	default:
+		w.Header().Set("Allow", d.allow())
+		d.Server.methodNotAllowed(w, r)
+	}
+}
+
+// allow returns the value for the Allow header
+func (d *webDAV) allow() string {
+	if d.Options.ReadOnly {
+		return "OPTIONS, GET, HEAD, PROPFIND"
+	}
+	return "OPTIONS, GET, HEAD, PROPFIND, MKCOL, PUT, DELETE, COPY, MOVE, LOCK, UNLOCK"
+}
+
+func (d *webDAV) handleOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", d.allow())
+	w.Header().Set("DAV", "1, 2")
+	w.Header().Set("MS-Author-Via", "DAV")
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(200)
+}
+
+// filesystem returns the filesystem for the local directory
+func (d *webDAV) filesystem() rootFS {
+	return rootFS{root: d.Root, policy: d.Options.Static.SymlinkPolicy}
+}
+
+// localPath returns the local path for the named file, which does not need to exist. Only the parent directory is
+// resolved, so if the file is a symbolic link then the link itself is modified rather than its target. Returns an error
+// if the file exists but is not permitted by the symlink policy, or if the parent directory is not permitted.
+func (d *webDAV) localPath(name string) (string, error) {
+	fsys := d.filesystem()
+	if name == "." {
+		return fsys.resolve(name)
+	}
+
+	parent, err := fsys.resolve(path.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(parent, path.Base(name))
+	if _, err := os.Lstat(full); err == nil {
+		if _, err := fsys.resolve(name); err != nil {
+			return "", fs.ErrPermission
+		}
+	}
+	return full, nil
+}
+
+// exists returns true if the named file exists and is permitted
+func (d *webDAV) exists(name string) bool {
+	_, err := fs.Stat(d.filesystem(), name)
+	return err == nil
+}
+
+// parentExists returns true if the parent directory of the local path exists
+func parentExists(local string) bool {
+	info, err := os.Stat(filepath.Dir(local))
+	return err == nil && info.IsDir()
+}
+
+// href returns the escaped URL path for the named file
+func (d *webDAV) href(name string, isDir bool) string {
+	p := d.Prefix + "/"
+	if name != "." {
+		p += name
+		if isDir {
+			p += "/"
+		}
+	}
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// checkLocks writes a 423 response and returns false if the named file is locked and the request does not submit the
+// lock token in its If header. If recursive is true then locks on any file within name are also checked. If member is
+// true then the request adds or removes name from its parent collection, so locks on the parent are also checked. If
+// the If header is malformed or none of its lists are true then a 400 or 412 response is written instead.
+func (d *webDAV) checkLocks(w http.ResponseWriter, r *http.Request, name string, recursive bool, member bool) bool {
+	tokens, status := d.evaluateIf(r)
+	if status != 0 {
+		d.Server.log.PInfo("WebDAV request If header not satisfied", map[string]interface{}{
+			"method": r.Method,
+			"name":   name,
+			"status": status,
+		})
+		w.WriteHeader(status)
+		return false
+	}
+	if d.Locks.permitted(name, tokens, recursive, member) {
+		return true
+	}
+	d.Server.log.PInfo("WebDAV request for locked resource", map[string]interface{}{
+		"method": r.Method,
+		"name":   name,
+	})
+	w.WriteHeader(http.StatusLocked)
+	return false
+}
+
+func (d *webDAV) handleMkcol(w http.ResponseWriter, r *http.Request, name string) {
+	if r.ContentLength > 0 {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	local, err := d.localPath(name)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if _, err := os.Lstat(local); err == nil {
+		w.Header().Set("Allow", d.allow())
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !parentExists(local) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if !d.checkLocks(w, r, name, false, true) {
+		return
+	}
+
+	if err := os.Mkdir(local, 0755); err != nil {
+		d.Server.log.PError("Error creating WebDAV collection", map[string]interface{}{
+			"name":  name,
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (d *webDAV) handlePut(w http.ResponseWriter, r *http.Request, name string) {
+	local, err := d.localPath(name)
+	if err != nil || !parentExists(local) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	existed := false
+	if info, err := os.Stat(local); err == nil {
+		if info.IsDir() {
+			w.Header().Set("Allow", d.allow())
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		existed = true
+	}
+	if !d.checkLocks(w, r, name, false, !existed) {
+		return
+	}
+
+	// Write to a temporary file first so that a failed upload does not replace an existing file
+	f, err := os.CreateTemp(filepath.Dir(local), ".webdav-*")
+	if err != nil {
+		d.Server.log.PError("Error creating temporary file for WebDAV upload", map[string]interface{}{
+			"name":  name,
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+	_, err = io.Copy(f, r.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), local)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		d.Server.log.PError("Error writing WebDAV upload", map[string]interface{}{
+			"name":  name,
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+
+	if existed {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (d *webDAV) handleDelete(w http.ResponseWriter, r *http.Request, name string) {
+	if name == "." {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	local, err := d.localPath(name)
+	if err != nil || !d.exists(name) {
+		d.Server.notFound(w, r)
+		return
+	}
+	if !d.checkLocks(w, r, name, true, true) {
+		return
+	}
+
+	if err := os.RemoveAll(local); err != nil {
+		d.Server.log.PError("Error deleting WebDAV resource", map[string]interface{}{
+			"name":  name,
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+	d.Locks.removeWithin(name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// destination returns the name of the file for the Destination header of the request. Returns a status code if the
+// destination is not valid.
+func (d *webDAV) destination(r *http.Request) (string, int) {
+	return d.urlName(r.Header.Get("Destination"), r)
+}
+
+// urlName returns the name of the file for a URL or absolute path sent in a header of the request. Returns a status code
+// if the URL is not for a file within this handler.
+func (d *webDAV) urlName(value string, r *http.Request) (string, int) {
+	destination, err := url.Parse(value)
+	if err != nil || destination.Path == "" {
+		return "", http.StatusBadRequest
+	}
+	if destination.Host != "" && destination.Host != r.Host {
+		return "", http.StatusBadGateway
+	}
+	if destination.Path != d.Prefix && !strings.HasPrefix(destination.Path, d.Prefix+"/") {
+		return "", http.StatusBadGateway
+	}
+
+	name, ok := resolvePath(strings.TrimPrefix(destination.Path, d.Prefix), d.Options.Static)
+	if !ok {
+		return "", http.StatusForbidden
+	}
+	return name, 0
+}
+
+func (d *webDAV) handleCopyMove(w http.ResponseWriter, r *http.Request, name string) {
+	source, err := d.localPath(name)
+	if err != nil || !d.exists(name) {
+		d.Server.notFound(w, r)
+		return
+	}
+	destinationName, status := d.destination(r)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	if name == "." || destinationName == "." || destinationName == name || strings.HasPrefix(destinationName, name+"/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	destination, err := d.localPath(destinationName)
+	if err != nil || !parentExists(destination) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	existed := d.exists(destinationName)
+	if r.Method == "MOVE" && !d.checkLocks(w, r, name, true, true) {
+		return
+	}
+	if !d.checkLocks(w, r, destinationName, true, !existed) {
+		return
+	}
+
+	if existed {
+		if strings.ToUpper(r.Header.Get("Overwrite")) == "F" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if err := os.RemoveAll(destination); err != nil {
+			d.Server.log.PError("Error replacing WebDAV resource", map[string]interface{}{
+				"name":  destinationName,
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+	}
+
+	if r.Method == "MOVE" {
+		err = os.Rename(source, destination)
+		if err == nil {
+			d.Locks.removeWithin(name)
+		}
+	} else {
+		err = d.copy(name, destination, r.Header.Get("Depth") != "0")
+	}
+	if err != nil {
+		d.Server.log.PError("Error copying or moving WebDAV resource", map[string]interface{}{
+			"method":      r.Method,
+			"name":        name,
+			"destination": destinationName,
+			"error":       err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+
+	if existed {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// copy will copy the named file or directory to the local path destination. Hidden files are not copied. If recursive
+// is false then only the directory itself is created.
+func (d *webDAV) copy(name string, destination string, recursive bool) error {
+	fsys := d.filesystem()
+	return fs.WalkDir(fsys, name, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if current != name && d.Options.Static.isHidden(entry.Name()) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		target := destination
+		if current != name {
+			target = filepath.Join(destination, filepath.FromSlash(strings.TrimPrefix(current, name+"/")))
+		}
+
+		info, err := fs.Stat(fsys, current)
+		if err != nil {
+			// Symbolic links not permitted by the policy are treated as if they do not exist
+			return nil
+		}
+		if info.IsDir() {
+			if !entry.IsDir() {
+				// Symbolic links to directories are not followed
+				return nil
+			}
+			if err := os.Mkdir(target, 0755); err != nil {
+				return err
+			}
+			if !recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := fsys.Open(current)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// davProperty describes a WebDAV property and its value as XML
+type davProperty struct {
+	Name  xml.Name
+	Value string
+}
+
+type davPropfind struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     *struct {
+		Names []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"DAV: prop"`
+}
+
+// properties returns the live properties of the named file
+func (d *webDAV) properties(name string, info fs.FileInfo) []davProperty {
+	davName := func(local string) xml.Name {
+		return xml.Name{Space: "DAV:", Local: local}
+	}
+	escape := func(value string) string {
+		buf := &bytes.Buffer{}
+		xml.EscapeText(buf, []byte(value))
+		return buf.String()
+	}
+
+	displayName := path.Base(name)
+	if name == "." {
+		displayName = path.Base(d.Prefix)
+	}
+	modTime := info.ModTime()
+	if modTime.IsZero() {
+		modTime = d.Mount.Registered
+	}
+
+	properties := []davProperty{
+		{davName("displayname"), escape(displayName)},
+		{davName("getlastmodified"), timeToHTTPDate(modTime.UTC())},
+		{davName("supportedlock"), "<D:lockentry><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockentry>" +
+			"<D:lockentry><D:lockscope><D:shared/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockentry>"},
+		{davName("lockdiscovery"), d.Locks.discovery(name)},
+	}
+	if info.IsDir() {
+		properties = append(properties, davProperty{davName("resourcetype"), "<D:collection/>"})
+	} else {
+		properties = append(properties,
+			davProperty{davName("resourcetype"), ""},
+			davProperty{davName("getcontentlength"), fmt.Sprintf("%d", info.Size())},
+			davProperty{davName("getcontenttype"), escape(d.Options.Static.mime(name))},
+			davProperty{davName("getetag"), escape(weakETag(info.Size(), modTime))},
+		)
+	}
+	return properties
+}
+
+func (d *webDAV) handlePropfind(w http.ResponseWriter, r *http.Request, name string) {
+	fsys := d.filesystem()
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		d.Server.notFound(w, r)
+		return
+	}
+
+	// A missing Depth header means infinity, which is not supported
+	depth := r.Header.Get("Depth")
+	if depth != "0" && depth != "1" {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(xml.Header + `<D:error xmlns:D="DAV:"><D:propfind-finite-depth/></D:error>`))
+		return
+	}
+
+	request := davPropfind{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := xml.Unmarshal(body, &request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	type resource struct {
+		Name string
+		Info fs.FileInfo
+	}
+	resources := []resource{{name, info}}
+	if info.IsDir() && depth == "1" {
+		entries, err := fs.ReadDir(fsys, name)
+		if err != nil {
+			d.Server.log.PError("Error reading WebDAV collection", map[string]interface{}{
+				"name":  name,
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+		for _, entry := range entries {
+			if d.Options.Static.isHidden(entry.Name()) {
+				continue
+			}
+			child := path.Join(name, entry.Name())
+			childInfo, err := fs.Stat(fsys, child)
+			if err != nil {
+				continue
+			}
+			resources = append(resources, resource{child, childInfo})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<D:multistatus xmlns:D="DAV:">`)
+	for _, res := range resources {
+		properties := d.properties(res.Name, res.Info)
+		buf.WriteString("<D:response><D:href>")
+		xml.EscapeText(buf, []byte(d.href(res.Name, res.Info.IsDir())))
+		buf.WriteString("</D:href>")
+
+		found := []davProperty{}
+		missing := []xml.Name{}
+		if request.Prop != nil && request.AllProp == nil {
+			for _, requested := range request.Prop.Names {
+				ok := false
+				for _, property := range properties {
+					if property.Name == requested.XMLName {
+						found = append(found, property)
+						ok = true
+						break
+					}
+				}
+				if !ok {
+					missing = append(missing, requested.XMLName)
+				}
+			}
+		} else {
+			found = properties
+		}
+
+		if len(found) > 0 {
+			buf.WriteString("<D:propstat><D:prop>")
+			for _, property := range found {
+				buf.WriteString("<D:" + property.Name.Local + ">")
+				if request.PropName == nil {
+					buf.WriteString(property.Value)
+				}
+				buf.WriteString("</D:" + property.Name.Local + ">")
+			}
+			buf.WriteString("</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>")
+		}
+		if len(missing) > 0 {
+			buf.WriteString("<D:propstat><D:prop>")
+			for _, property := range missing {
+				if property.Space == "DAV:" {
+					buf.WriteString("<D:" + property.Local + "/>")
+				} else {
+					buf.WriteString("<R:" + property.Local + ` xmlns:R="`)
+					xml.EscapeText(buf, []byte(property.Space))
+					buf.WriteString(`"/>`)
+				}
+			}
+			buf.WriteString("</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>")
+		}
+		buf.WriteString("</D:response>")
+	}
+	buf.WriteString("</D:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", buf.Len()))
+	w.WriteHeader(http.StatusMultiStatus)
+	io.Copy(w, buf)
+}
+
+type davLockInfo struct {
+	XMLName   xml.Name  `xml:"DAV: lockinfo"`
+	Exclusive *struct{} `xml:"DAV: lockscope>exclusive"`
+	Shared    *struct{} `xml:"DAV: lockscope>shared"`
+	Owner     struct {
+		InnerXML string `xml:",innerxml"`
+	} `xml:"DAV: owner"`
+}
+
+// lockTimeout returns the duration requested by the Timeout header, up to the maximum
+func (d *webDAV) lockTimeout(r *http.Request) time.Duration {
+	timeout := d.Options.maxLockTimeout()
+	for _, value := range strings.Split(r.Header.Get("Timeout"), ",") {
+		value = strings.TrimSpace(value)
+		if !strings.HasPrefix(value, "Second-") {
+			continue
+		}
+		var seconds int64
+		if _, err := fmt.Sscanf(value, "Second-%d", &seconds); err == nil && seconds > 0 {
+			if requested := time.Duration(seconds) * time.Second; requested < timeout {
+				timeout = requested
+			}
+			break
+		}
+	}
+	return timeout
+}
+
+func (d *webDAV) writeLockResponse(w http.ResponseWriter, lock davLock, status int) {
+	body := xml.Header + `<D:prop xmlns:D="DAV:"><D:lockdiscovery>` + lock.activeLock() + `</D:lockdiscovery></D:prop>`
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	w.Header().Set("Lock-Token", "<"+lock.Token+">")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+func (d *webDAV) handleLock(w http.ResponseWriter, r *http.Request, name string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	timeout := d.lockTimeout(r)
+
+	// A request without a body refreshes an existing lock
+	if len(bytes.TrimSpace(body)) == 0 {
+		tokens, status := d.evaluateIf(r)
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		lock, ok := d.Locks.refresh(name, tokens, timeout)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		d.writeLockResponse(w, lock, 200)
+		return
+	}
+
+	info := davLockInfo{}
+	if err := xml.Unmarshal(body, &info); err != nil || (info.Exclusive == nil) == (info.Shared == nil) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	depth := r.Header.Get("Depth")
+	if depth != "" && depth != "0" && depth != "infinity" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	local, err := d.localPath(name)
+	if err != nil || !parentExists(local) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if !d.exists(name) && !d.checkLocks(w, r, name, false, true) {
+		return
+	}
+
+	lock, ok := d.Locks.create(davLock{
+		Name:      name,
+		Infinite:  depth != "0",
+		Exclusive: info.Exclusive != nil,
+		Owner:     info.Owner.InnerXML,
+		Timeout:   timeout,
+	})
+	if !ok {
+		w.WriteHeader(http.StatusLocked)
+		return
+	}
+
+	// Locking a file that does not exist creates an empty file
+	status := 200
+	if !d.exists(name) {
+		f, err := os.OpenFile(local, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			d.Locks.remove(name, lock.Token)
+			d.Server.log.PError("Error creating locked WebDAV resource", map[string]interface{}{
+				"name":  name,
+				"error": err.Error(),
+			})
+			w.WriteHeader(500)
+			return
+		}
+		f.Close()
+		status = http.StatusCreated
+	}
+
+	d.writeLockResponse(w, lock, status)
+}
+
+func (d *webDAV) handleUnlock(w http.ResponseWriter, r *http.Request, name string) {
+	token := strings.Trim(strings.TrimSpace(r.Header.Get("Lock-Token")), "<>")
+	if token == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !d.Locks.remove(name, token) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
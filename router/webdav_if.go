@@ -0,0 +1,212 @@
+package router
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// davCondition describes a single condition of a list in a WebDAV If header
+type davCondition struct {
+	// If the condition is negated
+	Not bool
+	// The state token, such as a lock token. Empty if the condition is an entity tag.
+	Token string
+	// The entity tag. Empty if the condition is a state token.
+	ETag string
+}
+
+// davIfList describes a list of conditions in a WebDAV If header, which is true if all of its conditions are true
+type davIfList struct {
+	// The URL of the resource the list applies to, or empty if it applies to the resource of the request
+	Resource   string
+	Conditions []davCondition
+}
+
+// parseIfHeader parses the value of a WebDAV If header (RFC 4918 section 10.4), which is either a series of untagged
+// lists or a series of resource tags each followed by one or more lists. Returns false if the value is malformed.
+func parseIfHeader(value string) ([]davIfList, bool) {
+	lists := []davIfList{}
+	resource := ""
+	tagged := false
+	pendingTag := false
+
+	s := strings.TrimLeft(value, " \t")
+	for s != "" {
+		switch s[0] {
+		case '<':
+			// Untagged and tagged lists can not be mixed, and every resource tag must be followed by a list
+			if (len(lists) > 0 && !tagged) || pendingTag {
+				return nil, false
+			}
+			end := strings.IndexByte(s, '>')
+			if end <= 1 {
+				return nil, false
+			}
+			resource = s[1:end]
+			tagged = true
+			pendingTag = true
+			s = s[end+1:]
+		case '(':
+			list, rest, ok := parseIfList(s[1:])
+			if !ok {
+				return nil, false
+			}
+			list.Resource = resource
+			lists = append(lists, list)
+			pendingTag = false
+			s = rest
+		default:
+			return nil, false
+		}
+		s = strings.TrimLeft(s, " \t")
+	}
+
+	if len(lists) == 0 || pendingTag {
+		return nil, false
+	}
+	return lists, true
+}
+
+// parseIfList parses the conditions of a list that follow the opening parenthesis, returning the remainder of the value
+// after the closing parenthesis
+func parseIfList(s string) (davIfList, string, bool) {
+	list := davIfList{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return list, "", false
+		}
+		if s[0] == ')' {
+			return list, s[1:], len(list.Conditions) > 0
+		}
+
+		condition := davCondition{}
+		if len(s) > 3 && strings.EqualFold(s[0:3], "not") {
+			condition.Not = true
+			s = strings.TrimLeft(s[3:], " \t")
+			if s == "" {
+				return list, "", false
+			}
+		}
+
+		switch s[0] {
+		case '<':
+			end := strings.IndexByte(s, '>')
+			if end <= 1 {
+				return list, "", false
+			}
+			condition.Token = s[1:end]
+			s = s[end+1:]
+		case '[':
+			// The entity tag is a quoted string, which may itself contain a ']'
+			start := 1
+			if strings.HasPrefix(s[1:], "W/") {
+				start = 3
+			}
+			if len(s) <= start || s[start] != '"' {
+				return list, "", false
+			}
+			quote := strings.IndexByte(s[start+1:], '"')
+			if quote == -1 {
+				return list, "", false
+			}
+			end := start + 1 + quote + 1
+			if len(s) <= end || s[end] != ']' {
+				return list, "", false
+			}
+			condition.ETag = s[1:end]
+			s = s[end+1:]
+		default:
+			return list, "", false
+		}
+		list.Conditions = append(list.Conditions, condition)
+	}
+}
+
+// evaluateIf evaluates the If header of the request. Returns the lock tokens submitted by the request, which are the
+// tokens in lists that are true and not negated, or a status code if the header is malformed or none of its lists are
+// true. Untagged lists apply to the file of the request, or for COPY and MOVE requests, either the file or its
+// destination.
+func (d *webDAV) evaluateIf(r *http.Request) (map[string]bool, int) {
+	tokens := map[string]bool{}
+	value := r.Header.Get("If")
+	if value == "" {
+		return tokens, 0
+	}
+
+	lists, ok := parseIfHeader(value)
+	if !ok {
+		return nil, http.StatusBadRequest
+	}
+
+	requestNames := []string{}
+	if name, ok := resolvePath(strings.TrimPrefix(r.URL.Path, "/"), d.Options.Static); ok {
+		requestNames = append(requestNames, name)
+	}
+	if r.Method == "COPY" || r.Method == "MOVE" {
+		if name, status := d.destination(r); status == 0 {
+			requestNames = append(requestNames, name)
+		}
+	}
+
+	matched := false
+	for _, list := range lists {
+		names := requestNames
+		if list.Resource != "" {
+			name, status := d.urlName(list.Resource, r)
+			if status != 0 {
+				continue
+			}
+			names = []string{name}
+		}
+
+		for _, name := range names {
+			if !d.listMatches(list, name) {
+				continue
+			}
+			matched = true
+			for _, condition := range list.Conditions {
+				if condition.Token != "" && !condition.Not {
+					tokens[condition.Token] = true
+				}
+			}
+			break
+		}
+	}
+	if !matched {
+		return nil, http.StatusPreconditionFailed
+	}
+	return tokens, 0
+}
+
+// listMatches returns true if every condition in the list is true for the named file. A state token is true if it is
+// the token of a lock on the file, on a file within it, or on its parent collection. An entity tag is true if it
+// matches the entity tag of the file.
+func (d *webDAV) listMatches(list davIfList, name string) bool {
+	for _, condition := range list.Conditions {
+		var match bool
+		if condition.Token != "" {
+			match = d.Locks.holds(name, condition.Token)
+		} else {
+			match = condition.ETag == d.etag(name)
+		}
+		if match == condition.Not {
+			return false
+		}
+	}
+	return true
+}
+
+// etag returns the entity tag of the named file, or an empty string if it does not exist or is a directory
+func (d *webDAV) etag(name string) string {
+	info, err := fs.Stat(d.filesystem(), name)
+	if err != nil || info.IsDir() {
+		return ""
+	}
+	modTime := info.ModTime()
+	if modTime.IsZero() {
+		modTime = d.Mount.Registered
+	}
+	return weakETag(info.Size(), modTime)
+}
@@ -0,0 +1,194 @@
+package router
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// davLock describes a WebDAV write lock
+type davLock struct {
+	Token string
+	// The name of the locked file or directory
+	Name string
+	// If the lock applies to all files within Name
+	Infinite  bool
+	Exclusive bool
+	// The owner provided by the client, as XML
+	Owner   string
+	Timeout time.Duration
+	Expires time.Time
+}
+
+// covers returns true if the lock applies to the named file
+func (l davLock) covers(name string) bool {
+	return l.Name == name || (l.Infinite && isWithin(name, l.Name))
+}
+
+// activeLock returns the lock as a DAV:activelock element
+func (l davLock) activeLock() string {
+	scope := "<D:shared/>"
+	if l.Exclusive {
+		scope = "<D:exclusive/>"
+	}
+	depth := "0"
+	if l.Infinite {
+		depth = "infinity"
+	}
+	owner := ""
+	if l.Owner != "" {
+		owner = "<D:owner>" + l.Owner + "</D:owner>"
+	}
+	token := &bytes.Buffer{}
+	xml.EscapeText(token, []byte(l.Token))
+
+	return "<D:activelock><D:locktype><D:write/></D:locktype><D:lockscope>" + scope + "</D:lockscope>" +
+		"<D:depth>" + depth + "</D:depth>" + owner +
+		fmt.Sprintf("<D:timeout>Second-%d</D:timeout>", int(l.Timeout.Seconds())) +
+		"<D:locktoken><D:href>" + token.String() + "</D:href></D:locktoken></D:activelock>"
+}
+
+// isWithin returns true if name is parent or a file within parent
+func isWithin(name string, parent string) bool {
+	return parent == "." || name == parent || strings.HasPrefix(name, parent+"/")
+}
+
+// davLocks holds the WebDAV locks for a handler in memory
+type davLocks struct {
+	lock  *sync.Mutex
+	locks map[string]davLock
+}
+
+func newDAVLocks() *davLocks {
+	return &davLocks{
+		lock:  &sync.Mutex{},
+		locks: map[string]davLock{},
+	}
+}
+
+// expire removes any expired locks. The caller must hold the lock.
+func (l *davLocks) expire() {
+	now := time.Now()
+	for token, lock := range l.locks {
+		if now.After(lock.Expires) {
+			delete(l.locks, token)
+		}
+	}
+}
+
+// create adds a new lock, returning false if it conflicts with an existing lock
+func (l *davLocks) create(lock davLock) (davLock, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.expire()
+
+	for _, existing := range l.locks {
+		overlaps := existing.covers(lock.Name) || (lock.Infinite && isWithin(existing.Name, lock.Name))
+		if overlaps && (existing.Exclusive || lock.Exclusive) {
+			return davLock{}, false
+		}
+	}
+
+	tokenData := make([]byte, 16)
+	rand.Read(tokenData)
+	tokenData[6] = (tokenData[6] & 0x0f) | 0x40
+	tokenData[8] = (tokenData[8] & 0x3f) | 0x80
+	lock.Token = fmt.Sprintf("opaquelocktoken:%x-%x-%x-%x-%x", tokenData[0:4], tokenData[4:6], tokenData[6:8], tokenData[8:10], tokenData[10:16])
+	lock.Expires = time.Now().Add(lock.Timeout)
+	l.locks[lock.Token] = lock
+	return lock, true
+}
+
+// refresh extends the timeout of the lock on name whose token is in tokens
+func (l *davLocks) refresh(name string, tokens map[string]bool, timeout time.Duration) (davLock, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.expire()
+
+	for token, lock := range l.locks {
+		if lock.covers(name) && tokens[token] {
+			lock.Timeout = timeout
+			lock.Expires = time.Now().Add(timeout)
+			l.locks[token] = lock
+			return lock, true
+		}
+	}
+	return davLock{}, false
+}
+
+// remove deletes the lock with token, returning false if no such lock applies to name
+func (l *davLocks) remove(name string, token string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.expire()
+
+	lock, ok := l.locks[token]
+	if !ok || !lock.covers(name) {
+		return false
+	}
+	delete(l.locks, token)
+	return true
+}
+
+// removeWithin deletes all locks on name or any file within name
+func (l *davLocks) removeWithin(name string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for token, lock := range l.locks {
+		if isWithin(lock.Name, name) {
+			delete(l.locks, token)
+		}
+	}
+}
+
+// permitted returns true if every lock that applies to name has its token in tokens. If recursive is true then locks on
+// files within name must also be included. If member is true then a lock on the parent collection must also be
+// included, as a lock on a collection protects its membership even with a depth of 0.
+func (l *davLocks) permitted(name string, tokens map[string]bool, recursive bool, member bool) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.expire()
+
+	for token, lock := range l.locks {
+		applies := lock.covers(name) || (recursive && isWithin(lock.Name, name)) ||
+			(member && name != "." && lock.Name == path.Dir(name))
+		if applies && !tokens[token] {
+			return false
+		}
+	}
+	return true
+}
+
+// holds returns true if token is the token of a lock on name, on a file within name, or on the parent collection of name
+func (l *davLocks) holds(name string, token string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.expire()
+
+	lock, ok := l.locks[token]
+	if !ok {
+		return false
+	}
+	return lock.covers(name) || isWithin(lock.Name, name) || (name != "." && lock.Name == path.Dir(name))
+}
+
+// discovery returns the DAV:activelock elements for all locks that apply to name
+func (l *davLocks) discovery(name string) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.expire()
+
+	result := ""
+	for _, lock := range l.locks {
+		if lock.covers(name) {
+			result += lock.activeLock()
+		}
+	}
+	return result
+}
@@ -0,0 +1,368 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web/router"
+)
+
+var webDAVLockBody = `<?xml version="1.0"?><D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype><D:owner>tester</D:owner></D:lockinfo>`
+
+func testWebDAVRequest(t *testing.T, method string, url string, body string, headers map[string]string, expectedStatus int) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		panic(err)
+	}
+	if resp.StatusCode != expectedStatus {
+		t.Errorf("Unexpected status code for %s '%s'. Expected %d got %d: %s", method, url, expectedStatus, resp.StatusCode, data)
+	}
+	return resp, string(data)
+}
+
+func TestRouterWebDAVOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testWebDAVRequest(t, "OPTIONS", "http://"+listenAddress+"/dav/", "", nil, 200)
+	if resp.Header.Get("DAV") != "1, 2" || !strings.Contains(resp.Header.Get("Allow"), "PROPFIND") {
+		t.Errorf("Unexpected OPTIONS headers: %v", resp.Header)
+	}
+}
+
+func TestRouterWebDAVPropfind(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	os.WriteFile(path.Join(dir, ".secret"), []byte("secret"), 0644)
+	os.Mkdir(path.Join(dir, "docs"), 0755)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	_, body := testWebDAVRequest(t, "PROPFIND", "http://"+listenAddress+"/dav/", "", map[string]string{"Depth": "1"}, 207)
+	for _, expected := range []string{"<D:href>/dav/</D:href>", "<D:href>/dav/readme.txt</D:href>", "<D:href>/dav/docs/</D:href>", "<D:collection/>", "<D:getcontentlength>6</D:getcontentlength>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("PROPFIND response missing '%s': %s", expected, body)
+		}
+	}
+	if strings.Contains(body, ".secret") {
+		t.Errorf("PROPFIND response includes hidden file")
+	}
+
+	if _, body := testWebDAVRequest(t, "PROPFIND", "http://"+listenAddress+"/dav/", "", nil, 403); !strings.Contains(body, "<D:propfind-finite-depth/>") {
+		t.Errorf("Unexpected PROPFIND response for infinite depth: %s", body)
+	}
+	testWebDAVRequest(t, "PROPFIND", "http://"+listenAddress+"/dav/missing", "", map[string]string{"Depth": "0"}, 404)
+
+	_, body = testWebDAVRequest(t, "PROPFIND", "http://"+listenAddress+"/dav/readme.txt", `<?xml version="1.0"?><D:propfind xmlns:D="DAV:"><D:prop><D:getetag/><X:custom xmlns:X="urn:x"/></D:prop></D:propfind>`, map[string]string{"Depth": "0"}, 207)
+	if !strings.Contains(body, "<D:getetag>") || strings.Contains(body, "<D:displayname>") || !strings.Contains(body, `<R:custom xmlns:R="urn:x"/>`) || !strings.Contains(body, "404 Not Found") {
+		t.Errorf("Unexpected PROPFIND prop response: %s", body)
+	}
+}
+
+func TestRouterWebDAVGet(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	os.WriteFile(path.Join(dir, ".secret"), []byte("secret"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	if _, body := testWebDAVRequest(t, "GET", "http://"+listenAddress+"/dav/readme.txt", "", nil, 200); body != "readme" {
+		t.Errorf("Unexpected GET response '%s'", body)
+	}
+	testWebDAVRequest(t, "GET", "http://"+listenAddress+"/dav/.secret", "", nil, 404)
+}
+
+func TestRouterWebDAVWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testWebDAVRequest(t, "MKCOL", "http://"+listenAddress+"/dav/new", "", nil, 201)
+	testWebDAVRequest(t, "MKCOL", "http://"+listenAddress+"/dav/new", "", nil, 405)
+	testWebDAVRequest(t, "MKCOL", "http://"+listenAddress+"/dav/missing/new", "", nil, 409)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/new/file.txt", "hello", nil, 201)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/new/file.txt", "hello world", nil, 204)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/missing/file.txt", "hello", nil, 409)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/.env", "hello", nil, 403)
+	if data, _ := os.ReadFile(path.Join(dir, "new", "file.txt")); string(data) != "hello world" {
+		t.Errorf("Unexpected file content after PUT '%s'", data)
+	}
+}
+
+func TestRouterWebDAVCopyMove(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	os.Mkdir(path.Join(dir, "new"), 0755)
+	os.WriteFile(path.Join(dir, "new", "file.txt"), []byte("hello world"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testWebDAVRequest(t, "COPY", "http://"+listenAddress+"/dav/new", "", map[string]string{"Destination": "/dav/copy"}, 201)
+	if data, _ := os.ReadFile(path.Join(dir, "copy", "file.txt")); string(data) != "hello world" {
+		t.Errorf("Unexpected file content after COPY '%s'", data)
+	}
+	testWebDAVRequest(t, "COPY", "http://"+listenAddress+"/dav/readme.txt", "", map[string]string{"Destination": "/dav/copy/file.txt", "Overwrite": "F"}, 412)
+	testWebDAVRequest(t, "COPY", "http://"+listenAddress+"/dav/readme.txt", "", map[string]string{"Destination": "http://example.com/other/file.txt"}, 502)
+	testWebDAVRequest(t, "COPY", "http://"+listenAddress+"/dav/readme.txt", "", map[string]string{"Destination": "/dav/../../escape.txt"}, 201)
+	if _, err := os.Stat(path.Join(dir, "escape.txt")); err != nil {
+		t.Errorf("Destination with dot segments should resolve within the root")
+	}
+	testWebDAVRequest(t, "COPY", "http://"+listenAddress+"/dav/new", "", map[string]string{"Destination": "/dav/new/inner"}, 403)
+
+	testWebDAVRequest(t, "MOVE", "http://"+listenAddress+"/dav/copy", "", map[string]string{"Destination": "http://" + listenAddress + "/dav/moved/"}, 201)
+	if _, err := os.Stat(path.Join(dir, "moved", "file.txt")); err != nil {
+		t.Errorf("File not moved")
+	}
+}
+
+func TestRouterWebDAVDelete(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.Mkdir(path.Join(dir, "docs"), 0755)
+	os.WriteFile(path.Join(dir, "docs", "a.txt"), []byte("a"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testWebDAVRequest(t, "DELETE", "http://"+listenAddress+"/dav/docs", "", nil, 204)
+	testWebDAVRequest(t, "DELETE", "http://"+listenAddress+"/dav/docs", "", nil, 404)
+	testWebDAVRequest(t, "DELETE", "http://"+listenAddress+"/dav/", "", nil, 403)
+	if _, err := os.Stat(path.Join(dir, "docs")); err == nil {
+		t.Errorf("Directory not deleted")
+	}
+}
+
+func TestRouterWebDAVLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	os.Mkdir(path.Join(dir, "docs"), 0755)
+	os.WriteFile(path.Join(dir, "docs", "a.txt"), []byte("a"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav/", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, body := testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/docs", webDAVLockBody, map[string]string{"Timeout": "Second-600"}, 200)
+	token := strings.Trim(resp.Header.Get("Lock-Token"), "<>")
+	if !strings.HasPrefix(token, "opaquelocktoken:") || !strings.Contains(body, "<D:timeout>Second-600</D:timeout>") {
+		t.Fatalf("Unexpected LOCK response: %v %s", resp.Header, body)
+	}
+	testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/docs/a.txt", webDAVLockBody, nil, 423)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/docs/a.txt", "changed", nil, 423)
+	testWebDAVRequest(t, "DELETE", "http://"+listenAddress+"/dav/docs", "", nil, 423)
+	testWebDAVRequest(t, "MOVE", "http://"+listenAddress+"/dav/readme.txt", "", map[string]string{"Destination": "/dav/docs/readme.txt"}, 423)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/docs/a.txt", "changed", map[string]string{"If": "(<" + token + ">)"}, 204)
+	testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/docs", "", map[string]string{"If": "(<" + token + ">)"}, 200)
+	if _, body := testWebDAVRequest(t, "PROPFIND", "http://"+listenAddress+"/dav/docs", "", map[string]string{"Depth": "0"}, 207); !strings.Contains(body, token) {
+		t.Errorf("PROPFIND does not include lock")
+	}
+	testWebDAVRequest(t, "UNLOCK", "http://"+listenAddress+"/dav/docs", "", map[string]string{"Lock-Token": "<opaquelocktoken:nope>"}, 409)
+	testWebDAVRequest(t, "UNLOCK", "http://"+listenAddress+"/dav/docs", "", map[string]string{"Lock-Token": "<" + token + ">"}, 204)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/docs/a.txt", "unlocked", nil, 204)
+
+	testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/locked.txt", webDAVLockBody, map[string]string{"Depth": "0"}, 201)
+	if _, err := os.Stat(path.Join(dir, "locked.txt")); err != nil {
+		t.Errorf("LOCK did not create file")
+	}
+}
+
+func TestRouterWebDAVReadOnly(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav", router.WebDAVOptions{Static: router.DefaultStaticOptions(), ReadOnly: true})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	for _, method := range []string{"PUT", "DELETE", "MKCOL", "MOVE", "COPY", "LOCK", "UNLOCK"} {
+		testWebDAVRequest(t, method, "http://"+listenAddress+"/dav/readme.txt", "", nil, 405)
+	}
+
+	if _, body := testWebDAVRequest(t, "PROPFIND", "http://"+listenAddress+"/dav", "", map[string]string{"Depth": "1"}, 207); !strings.Contains(body, "/dav/readme.txt") {
+		t.Errorf("Unexpected read-only PROPFIND response: %s", body)
+	}
+	if data, _ := os.ReadFile(path.Join(dir, "readme.txt")); string(data) != "readme" {
+		t.Errorf("File modified on read-only handler")
+	}
+}
+
+func TestRouterWebDAVSymlink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.Mkdir(path.Join(dir, "target"), 0755)
+	os.WriteFile(path.Join(dir, "target", "a.txt"), []byte("a"), 0644)
+	os.Symlink(path.Join(dir, "target"), path.Join(dir, "link"))
+	os.Symlink(path.Join(dir, "target"), path.Join(dir, "other"))
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	testWebDAVRequest(t, "MOVE", "http://"+listenAddress+"/dav/link", "", map[string]string{"Destination": "/dav/moved"}, 201)
+	if info, err := os.Lstat(path.Join(dir, "moved")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("MOVE of symbolic link did not move the link")
+	}
+	if _, err := os.Stat(path.Join(dir, "target", "a.txt")); err != nil {
+		t.Errorf("MOVE of symbolic link moved its target")
+	}
+
+	testWebDAVRequest(t, "DELETE", "http://"+listenAddress+"/dav/other", "", nil, 204)
+	if _, err := os.Lstat(path.Join(dir, "other")); err == nil {
+		t.Errorf("DELETE of symbolic link did not remove the link")
+	}
+	if _, err := os.Stat(path.Join(dir, "target", "a.txt")); err != nil {
+		t.Errorf("DELETE of symbolic link removed its target")
+	}
+}
+
+func TestRouterWebDAVCollectionLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.Mkdir(path.Join(dir, "docs"), 0755)
+	os.WriteFile(path.Join(dir, "docs", "a.txt"), []byte("a"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/docs", webDAVLockBody, map[string]string{"Depth": "0"}, 200)
+	ifHeader := "(" + resp.Header.Get("Lock-Token") + ")"
+
+	// A depth 0 lock protects the membership of the collection, but not the contents of its members
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/docs/a.txt", "changed", nil, 204)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/docs/b.txt", "b", nil, 423)
+	testWebDAVRequest(t, "MKCOL", "http://"+listenAddress+"/dav/docs/sub", "", nil, 423)
+	testWebDAVRequest(t, "DELETE", "http://"+listenAddress+"/dav/docs/a.txt", "", nil, 423)
+	testWebDAVRequest(t, "MOVE", "http://"+listenAddress+"/dav/docs/a.txt", "", map[string]string{"Destination": "/dav/a.txt"}, 423)
+	testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/docs/c.txt", webDAVLockBody, map[string]string{"Depth": "0"}, 423)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/docs/b.txt", "b", map[string]string{"If": ifHeader}, 201)
+}
+
+func TestRouterWebDAVIfHeader(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(path.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	os.Mkdir(path.Join(dir, "docs"), 0755)
+	os.WriteFile(path.Join(dir, "docs", "a.txt"), []byte("a"), 0644)
+	listenAddress := getListenAddress()
+
+	server := router.New()
+	server.ServeWebDAV(dir, "/dav", router.WebDAVOptions{Static: router.DefaultStaticOptions()})
+	go func() {
+		server.ListenAndServe(listenAddress)
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	resp, _ := testWebDAVRequest(t, "LOCK", "http://"+listenAddress+"/dav/docs", webDAVLockBody, nil, 200)
+	token := resp.Header.Get("Lock-Token")
+	url := "http://" + listenAddress + "/dav/docs/a.txt"
+
+	// The token must be in a list that is true, not merely appear somewhere in the header
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "(Not " + token + ")"}, 412)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": `(["` + token + `"])`}, 412)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": token + " (<opaquelocktoken:nope>)"}, 412)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "<http://" + listenAddress + "/dav/readme.txt> (" + token + ")"}, 412)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "(" + token + ` [W/"nope"])`}, 412)
+
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "(<opaquelocktoken:nope>) (" + token + ")"}, 204)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "(" + token + ` Not [W/"nope"])`}, 204)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "<" + url + "> (" + token + ")"}, 204)
+	testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": "</dav/docs> (" + token + ")"}, 204)
+
+	// Entity tags are compared with the current entity tag of the file
+	resp, _ = testWebDAVRequest(t, "GET", "http://"+listenAddress+"/dav/readme.txt", "", nil, 200)
+	etag := resp.Header.Get("ETag")
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/readme.txt", "changed", map[string]string{"If": `([W/"nope"])`}, 412)
+	testWebDAVRequest(t, "PUT", "http://"+listenAddress+"/dav/readme.txt", "changed", map[string]string{"If": "([" + etag + "])"}, 204)
+
+	for _, malformed := range []string{"(" + token, "<" + url + ">", "(" + token + ") <" + url + "> (" + token + ")", "()", "(" + token + " [nope])", token} {
+		testWebDAVRequest(t, "PUT", url, "a", map[string]string{"If": malformed}, 400)
+	}
+}
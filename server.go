@@ -1,8 +1,12 @@
 package web
 
 import (
+	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,10 +41,14 @@ type Server struct {
 	// The handler called when a request exceed the configured maximum per second limit. Defaults to a plain HTTP 429
 	// with "Too many requests" as the body.
 	RateLimitedHandler func(w http.ResponseWriter, r *http.Request)
-	// Additional options for the server
+	// Additional options for the server. Not used by servers returned by Host, which use the options of the server they
+	// were created from.
 	Options ServerOptions
 
 	router       *router.Server
+	parent       *Server
+	hosts        map[string]*Server
+	hostLock     *sync.Mutex
 	listener     net.Listener
 	shuttingDown bool
 	limits       map[string]*rate.Limiter
@@ -56,6 +64,14 @@ type ServerOptions struct {
 	RequestLogLevel logtic.LogLevel
 	// If true then the server will not try to reply with chunked data for a HTTP range request
 	IgnoreHTTPRangeRequests bool
+	// Options for compressing responses from API, HTTP, and HTTPEasy handles. Compression is disabled by default.
+	Compression CompressionOptions
+	// The maximum length of any request body for handles that do not specify their own MaxBodyLength. The default value
+	// of 0 does not limit request bodies. Unless Decompression is enabled this limits only the compressed bytes of a
+	// body, handles that decompress the body themselves must limit how much they read.
+	MaxBodyLength uint64
+	// Options for decompressing request bodies. Decompression is disabled by default.
+	Decompression DecompressionOptions
 }
 
 // New create a new server object that will bind to the provided address. Does not accept incoming connections until
@@ -71,6 +87,8 @@ func New(bindAddress string) *Server {
 		router:    httpRouter,
 		limits:    map[string]*rate.Limiter{},
 		limitLock: &sync.Mutex{},
+		hosts:     map[string]*Server{},
+		hostLock:  &sync.Mutex{},
 	}
 	httpRouter.SetNotFoundHandle(server.notFoundHandle)
 	httpRouter.SetMethodNotAllowedHandle(server.methodNotAllowedHandle)
@@ -99,6 +117,8 @@ func NewListener(listener net.Listener) *Server {
 		listener:  listener,
 		limits:    map[string]*rate.Limiter{},
 		limitLock: &sync.Mutex{},
+		hosts:     map[string]*Server{},
+		hostLock:  &sync.Mutex{},
 	}
 	httpRouter.SetNotFoundHandle(server.notFoundHandle)
 	httpRouter.SetMethodNotAllowedHandle(server.methodNotAllowedHandle)
@@ -116,8 +136,13 @@ func NewListener(listener net.Listener) *Server {
 }
 
 // Start will start the web server and listen on the socket address. This method blocks.
-// If a server is stopped using the Stop() method, this returns no error.
+// If a server is stopped using the Stop() method, this returns no error. Returns an error for servers returned by Host,
+// which are served by the server they were created from.
 func (s *Server) Start() error {
+	if s.parent != nil {
+		return errVirtualHost
+	}
+	s.options().Decompression.validate()
 	if s.BindAddress != "" {
 		listener, err := net.Listen("tcp", s.BindAddress)
 		if err != nil {
@@ -144,16 +169,216 @@ func (s *Server) Start() error {
 	return nil
 }
 
-// Stop will stop the server. The Start() method will return without an error after stopping.
+// Stop will stop the server. The Start() method will return without an error after stopping. Does nothing for servers
+// that are not started, including servers returned by Host.
 func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
 	log.Warn("Stopping HTTP server")
 	s.shuttingDown = true
 	s.ListenPort = 0
 	s.listener.Close()
 }
 
+// Host returns a server with its own handles that will be used for requests to the given host. Requests for hosts
+// without their own handles use the handles of this server.
+//
+// The pattern is either an exact host name, such as "example.com", or a wildcard, such as "*.example.com", which
+// matches any host ending with ".example.com". The portion of the host matched by the wildcard is included in the
+// request parameters as "host", or with the name that follows the asterisk, such as "*tenant.example.com". Exact hosts
+// take priority over wildcards. Hosts are not case sensitive and any port in the request is ignored.
+//
+// The returned server shares the listener, options, and rate limits of this server and can not be started or stopped on
+// its own. Changes to the Options of the returned server have no effect, set the Options of this server instead.
+// If the NotFoundHandler or MethodNotAllowedHandler of the returned server are not set, the handlers of this server
+// are used. Calling Host more than once with the same pattern returns the same server.
+//
+// Will panic if the pattern is empty or invalid.
+func (s *Server) Host(pattern string) *Server {
+	root := s
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	root.hostLock.Lock()
+	defer root.hostLock.Unlock()
+	key := hostKey(pattern)
+	if existing, ok := root.hosts[key]; ok {
+		return existing
+	}
+
+	hostRouter := root.router.Host(pattern)
+	server := &Server{
+		router:    hostRouter,
+		parent:    root,
+		limits:    root.limits,
+		limitLock: root.limitLock,
+	}
+	hostRouter.SetNotFoundHandle(server.notFoundHandle)
+	hostRouter.SetMethodNotAllowedHandle(server.methodNotAllowedHandle)
+	server.API = API{
+		server: server,
+	}
+	server.HTTPEasy = HTTPEasy{
+		server: server,
+	}
+	server.HTTP = HTTP{
+		server: server,
+	}
+	root.hosts[key] = server
+
+	return server
+}
+
+var errVirtualHost = errors.New("virtual host servers can not be started")
+
+// hostKey returns the pattern normalized the same way as the router does, so that patterns for the same routing table
+// return the same server
+func hostKey(pattern string) string {
+	pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+	if strings.HasPrefix(pattern, "*.") {
+		pattern = "*host" + pattern[1:]
+	}
+	return pattern
+}
+
+// options returns the options for the server, which are the options of the parent server for virtual hosts
+func (s *Server) options() ServerOptions {
+	if s.parent != nil {
+		return s.parent.Options
+	}
+	return s.Options
+}
+
+// URLFor will build the path for the route registered with the given name, substituting any parameter or wildcard
+// segments with the matching value from parameters. Keys in parameters do not include the ':' or '*'. See
+// [web.HandleOptions] for naming a route.
+//
+// Returns an error if no route has the given name, if a value for a parameter is missing, or if a value does not
+// satisfy the constraint of the parameter.
+func (s *Server) URLFor(name string, parameters map[string]string) (string, error) {
+	return s.router.URLFor(name, parameters)
+}
+
+// Routes returns information about every route registered on the server, sorted by host, path, and then method. Routes
+// for any virtual hosts created from the server are included.
+func (s *Server) Routes() []router.RouteInfo {
+	return s.router.Routes()
+}
+
+// Mount will pass all requests for prefix, or any path below prefix, to handler regardless of the method. The prefix
+// is removed from the request path before it is passed to handler, so a request for "/admin/users" to a handler
+// mounted at "/admin" will have the path "/users". Requests to mounted handlers are rate limited and logged like any
+// other request.
+//
+// Mounted handlers take priority over any other handles, and longer prefixes take priority over shorter ones. Will
+// panic if prefix does not begin with a '/', is the root path, or if a handler is already mounted at prefix.
+func (s *Server) Mount(prefix string, handler http.Handler) {
+	s.router.Mount(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isRateLimited(w, r) {
+			return
+		}
+
+		start := time.Now()
+		handler.ServeHTTP(w, r)
+		log.PWrite(s.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
+			"remote_addr": RealRemoteAddr(r),
+			"method":      r.Method,
+			"url":         r.URL,
+			"elapsed":     time.Since(start).String(),
+		})
+	}))
+}
+
+// Unmount will remove the handler mounted at prefix. Does nothing if no handler is mounted at prefix.
+func (s *Server) Unmount(prefix string) {
+	s.router.Unmount(prefix)
+}
+
+// SetPathPolicy will set the policy for request paths that do not exactly match a registered path, such as redirecting
+// requests to the registered trailing slash form. See [router.PathPolicy] for the available options. By default requests
+// must match the registered path exactly.
+func (s *Server) SetPathPolicy(policy router.PathPolicy) {
+	s.router.SetPathPolicy(policy)
+}
+
+// SetRules will replace all redirect and rewrite rules for the server. Rules are evaluated in order before the request
+// is routed, and only the first matching rule is applied. Rules set on a virtual host created with Host apply only to
+// that host. See [router.Rule] for the format of a rule.
+//
+// Returns an error if any rule is invalid, in which case the existing rules are not changed. SetRules may be called
+// even while the server is running.
+func (s *Server) SetRules(rules []router.Rule) error {
+	return s.router.SetRules(rules)
+}
+
+// Rules returns a copy of the redirect and rewrite rules for the server
+func (s *Server) Rules() []router.Rule {
+	return s.router.Rules()
+}
+
+// LoadRules will read a JSON array of [router.Rule] objects from the file at filePath and replace all redirect and
+// rewrite rules for the server with them. LoadRules may be called again at any time to reload the rules, for example
+// when the file changes.
+//
+// For example:
+//
+//	[
+//	    { "pattern": "/old", "target": "/new", "status": 301 },
+//	    { "match": "regex", "pattern": "/posts/(\\d+)\\.html", "target": "/articles/${1}" }
+//	]
+func (s *Server) LoadRules(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.PError("Error reading rules file", map[string]interface{}{
+			"file_path": filePath,
+			"error":     err.Error(),
+		})
+		return err
+	}
+
+	rules := []router.Rule{}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.PError("Error decoding rules file", map[string]interface{}{
+			"file_path": filePath,
+			"error":     err.Error(),
+		})
+		return err
+	}
+
+	if err := s.SetRules(rules); err != nil {
+		log.PError("Invalid rule in rules file", map[string]interface{}{
+			"file_path": filePath,
+			"error":     err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+// Handler returns a http.Handler for the server, for use with other HTTP servers or with httptest. Requests are
+// handled exactly as they would be if the server was started, including for any virtual hosts. The server does not
+// need to be started to use the handler.
+func (s *Server) Handler() http.Handler {
+	s.options().Decompression.validate()
+	return s.router.Handler()
+}
+
+// RouteTable registers a GET handle at path that renders the table of routes registered on the server. The table is
+// rendered as JSON if the request accepts "application/json" or includes the query "format=json", otherwise as HTML.
+//
+// The route table may reveal information about your application, you should use the AuthenticateMethod of options to
+// restrict access to operators.
+func (s *Server) RouteTable(path string, options HandleOptions) {
+	routeTableHandle := s.router.RouteTableHandle()
+	s.HTTP.GET(path, func(w http.ResponseWriter, r Request) {
+		routeTableHandle(w, router.Request{HTTP: r.HTTP, Parameters: r.Parameters})
+	}, options)
+}
+
 func (s *Server) notFoundHandle(w http.ResponseWriter, r *http.Request) {
-	log.PWrite(s.Options.RequestLogLevel, "HTTP Request", map[string]interface{}{
+	log.PWrite(s.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
 		"remote_addr": RealRemoteAddr(r),
 		"method":      r.Method,
 		"url":         r.URL,
@@ -164,12 +389,16 @@ func (s *Server) notFoundHandle(w http.ResponseWriter, r *http.Request) {
 		s.NotFoundHandler(w, r)
 		return
 	}
+	if s.parent != nil && s.parent.NotFoundHandler != nil {
+		s.parent.NotFoundHandler(w, r)
+		return
+	}
 	w.WriteHeader(404)
 	w.Write([]byte("Not found"))
 }
 
 func (s *Server) methodNotAllowedHandle(w http.ResponseWriter, r *http.Request) {
-	log.PWrite(s.Options.RequestLogLevel, "HTTP Request", map[string]interface{}{
+	log.PWrite(s.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
 		"remote_addr": RealRemoteAddr(r),
 		"method":      r.Method,
 		"url":         r.URL,
@@ -180,11 +409,58 @@ func (s *Server) methodNotAllowedHandle(w http.ResponseWriter, r *http.Request)
 		s.MethodNotAllowedHandler(w, r)
 		return
 	}
+	if s.parent != nil && s.parent.MethodNotAllowedHandler != nil {
+		s.parent.MethodNotAllowedHandler(w, r)
+		return
+	}
 	w.WriteHeader(405)
 	w.Write([]byte("Method not allowed"))
 }
 
+// mountedPreHandle performs the pre-handle, rate limit, body length, and authentication checks for a handler mounted
+// directly on the router. Returns the user data from AuthenticateMethod and true if the request should continue.
+func (s *Server) mountedPreHandle(w http.ResponseWriter, r *http.Request, options HandleOptions, kind string) (interface{}, bool) {
+	if options.PreHandle != nil {
+		if err := options.PreHandle(w, r); err != nil {
+			return nil, false
+		}
+	}
+
+	if s.isRateLimited(w, r) {
+		return nil, false
+	}
+
+	if status := s.prepareBody(w, r, options, kind); status != 0 {
+		w.WriteHeader(status)
+		return nil, false
+	}
+
+	if options.AuthenticateMethod == nil {
+		return nil, true
+	}
+	userData := options.AuthenticateMethod(r)
+	if !isUserdataNil(userData) {
+		return userData, true
+	}
+	if options.UnauthorizedMethod == nil {
+		log.PWarn("Rejected request to authenticated "+kind+" endpoint", map[string]interface{}{
+			"url":         r.URL,
+			"method":      r.Method,
+			"remote_addr": RealRemoteAddr(r),
+		})
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+	options.UnauthorizedMethod(w, r)
+	return nil, false
+}
+
 func (s *Server) isRateLimited(w http.ResponseWriter, r *http.Request) bool {
+	// Virtual hosts share the limits of the server they were created from
+	if s.parent != nil {
+		return s.parent.isRateLimited(w, r)
+	}
+
 	// If rate limiting is not configured return a new limiter for each connection
 	if s.Options.MaxRequestsPerSecond == 0 {
 		return false
@@ -207,7 +483,7 @@ func (s *Server) isRateLimited(w http.ResponseWriter, r *http.Request) bool {
 			"method":      r.Method,
 			"url":         r.URL,
 		})
-		log.PWrite(s.Options.RequestLogLevel, "HTTP Request", map[string]interface{}{
+		log.PWrite(s.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
 			"remote_addr": RealRemoteAddr(r),
 			"method":      r.Method,
 			"url":         r.URL,
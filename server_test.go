@@ -6,12 +6,15 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"path"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ecnepsnai/web"
+	"github.com/ecnepsnai/web/router"
 )
 
 func TestUnixSocket(t *testing.T) {
@@ -260,3 +263,276 @@ func TestMethodNotAllowed(t *testing.T) {
 		}
 	}()
 }
+
+func TestServerURLFor(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	apiHandle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		url, err := server.URLFor("widget_part", map[string]string{"id": request.Parameters["id"], "part": "wheel"})
+		if err != nil {
+			return nil, nil, web.ValidationError("%s", err.Error())
+		}
+		return url, nil, nil
+	}
+	easyHandle := func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{}
+	}
+
+	prefix := randomString(5)
+	server.API.GET("/"+prefix+"/widgets/:id<int>", apiHandle, web.HandleOptions{Name: "widget"})
+	server.HTTPEasy.GETHEAD("/"+prefix+"/widgets/:id<int>/parts/:part", easyHandle, web.HandleOptions{Name: "widget_part"})
+
+	url, err := server.URLFor("widget", map[string]string{"id": "5"})
+	if err != nil {
+		t.Fatalf("Error building URL: %s", err.Error())
+	}
+	if url != "/"+prefix+"/widgets/5" {
+		t.Fatalf("Unexpected URL '%s'", url)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d%s", server.ListenPort, url))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	expected := `{"data":"/` + prefix + `/widgets/5/parts/wheel"}`
+	if strings.TrimSpace(string(body)) != expected {
+		t.Fatalf("Unexpected response body. Expected '%s' got '%s'", expected, body)
+	}
+
+	if _, err := server.URLFor("widget", map[string]string{"id": "five"}); err == nil {
+		t.Fatalf("No error seen for invalid parameter value")
+	}
+}
+
+func TestServerRoutes(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	apiHandle := func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return true, nil, nil
+	}
+	socketHandle := func(request web.Request, conn *web.WSConn) {}
+	authenticate := func(request *http.Request) interface{} {
+		return 1
+	}
+
+	prefix := "/" + randomString(5)
+	server.API.GET(prefix+"/users", apiHandle, web.HandleOptions{AuthenticateMethod: authenticate, MaxBodyLength: 10})
+	server.Socket(prefix+"/ws", socketHandle, web.HandleOptions{})
+	server.HTTPEasy.Static(prefix+"/static/", t.TempDir())
+	server.RouteTable(prefix+"/routes", web.HandleOptions{Name: "routes"})
+
+	kinds := map[string]string{}
+	for _, route := range server.Routes() {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		kinds[route.Method+" "+route.Path] = route.Kind
+		if route.Path == prefix+"/users" {
+			if route.Options["authenticated"] != "true" || route.Options["max_body_length"] != "10" {
+				t.Errorf("Unexpected options for API route: %+v", route.Options)
+			}
+		}
+	}
+
+	expected := map[string]string{
+		"GET " + prefix + "/users":         web.RouteKindAPI,
+		"GET " + prefix + "/ws":            web.RouteKindSocket,
+		"GET " + prefix + "/static/*path":  "Static",
+		"HEAD " + prefix + "/static/*path": "Static",
+		"GET " + prefix + "/routes":        web.RouteKindHTTP,
+	}
+	for route, kind := range expected {
+		if kinds[route] != kind {
+			t.Errorf("Unexpected kind for route '%s'. Expected '%s' got '%s'", route, kind, kinds[route])
+		}
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d%s/routes?format=json", server.ListenPort, prefix))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected content type '%s'", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestServerHost(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	server.NotFoundHandler = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("default not found"))
+	}
+	server.HTTPEasy.GET("/", func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{Reader: io.NopCloser(strings.NewReader("default"))}
+	}, web.HandleOptions{})
+
+	example := server.Host("example.com")
+	example.HTTPEasy.GET("/", func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{Reader: io.NopCloser(strings.NewReader("example"))}
+	}, web.HandleOptions{})
+
+	tenants := server.Host("*tenant.example.com")
+	tenants.API.GET("/tenant", func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return request.Parameters["tenant"], nil, nil
+	}, web.HandleOptions{})
+	tenants.NotFoundHandler = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("tenant not found"))
+	}
+
+	if server.Host("EXAMPLE.com") != example {
+		t.Errorf("Different server returned for existing host")
+	}
+
+	check := func(host, path string, expectedStatus int, expected string) {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("http://localhost:%d%s", server.ListenPort, path), nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != expectedStatus {
+			t.Errorf("Unexpected status code for %s%s. Expected %d got %d", host, path, expectedStatus, resp.StatusCode)
+		}
+		if string(body) != expected {
+			t.Errorf("Unexpected response for %s%s. Expected '%s' got '%s'", host, path, expected, body)
+		}
+	}
+
+	check("localhost", "/", 200, "default")
+	check("example.com", "/", 200, "example")
+	check("example.com", "/nothing", 404, "default not found")
+	check("acme.example.com", "/tenant", 200, "{\"data\":\"acme\"}\n")
+	check("acme.example.com", "/", 404, "tenant not found")
+
+	if err := example.Start(); err == nil {
+		t.Errorf("No error seen when starting a virtual host")
+	}
+}
+
+func TestServerHostNormalized(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	example := server.Host("example.com")
+	if server.Host("Example.com.") != example {
+		t.Errorf("Different server returned for host with trailing dot")
+	}
+	if server.Host("*.example.com") != server.Host("*host.example.com.") {
+		t.Errorf("Different server returned for equivalent wildcard hosts")
+	}
+
+	// Virtual hosts are served by the server they were created from
+	example.Stop()
+	if err := example.Start(); err == nil {
+		t.Errorf("No error seen when starting a virtual host")
+	}
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/", server.ListenPort))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+}
+
+func TestServerMount(t *testing.T) {
+	t.Parallel()
+	server := web.New("127.0.0.1:0")
+
+	server.API.GET("/status", func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return "ok", nil, nil
+	}, web.HandleOptions{})
+	server.Mount("/files", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+
+	outer := http.NewServeMux()
+	outer.Handle("/", server.Handler())
+	test := httptest.NewServer(outer)
+	defer test.Close()
+
+	check := func(path, expected string) {
+		resp, err := http.Get(test.URL + path)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != expected {
+			t.Errorf("Unexpected response for %s. Expected '%s' got '%s'", path, expected, body)
+		}
+	}
+
+	check("/status", "{\"data\":\"ok\"}\n")
+	check("/files/a/b.txt", "/a/b.txt")
+
+	server.Unmount("/files")
+	check("/files/a/b.txt", "Not found")
+}
+
+func TestServerPathPolicy(t *testing.T) {
+	t.Parallel()
+	server := web.New("127.0.0.1:0")
+
+	server.API.GET("/users/", func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		return true, nil, nil
+	}, web.HandleOptions{})
+	server.SetPathPolicy(router.PathPolicy{RedirectTrailingSlash: true})
+
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, httptest.NewRequest("GET", "/users?page=1", nil))
+	if resp.Code != 301 {
+		t.Errorf("Unexpected status code. Expected 301 got %d", resp.Code)
+	}
+	if location := resp.Header().Get("Location"); location != "/users/?page=1" {
+		t.Errorf("Unexpected location. Expected '/users/?page=1' got '%s'", location)
+	}
+}
+
+func TestServerLoadRules(t *testing.T) {
+	t.Parallel()
+	server := web.New("127.0.0.1:0")
+
+	server.HTTPEasy.GET("/new", func(request web.Request) web.HTTPResponse {
+		return web.HTTPResponse{Reader: io.NopCloser(strings.NewReader("new " + request.HTTP.URL.RawQuery))}
+	}, web.HandleOptions{})
+
+	rulesPath := path.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(rulesPath, []byte(`[{"pattern": "/old", "target": "/new", "status": 308}, {"match": "prefix", "pattern": "/legacy/", "target": "/new"}]`), 0644); err != nil {
+		panic(err)
+	}
+	if err := server.LoadRules(rulesPath); err != nil {
+		t.Fatalf("Unexpected error loading rules: %s", err.Error())
+	}
+
+	resp := httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, httptest.NewRequest("POST", "/old?a=1", nil))
+	if resp.Code != 308 || resp.Header().Get("Location") != "/new?a=1" {
+		t.Errorf("Unexpected redirect. Expected 308 to '/new?a=1' got %d to '%s'", resp.Code, resp.Header().Get("Location"))
+	}
+
+	resp = httptest.NewRecorder()
+	server.Handler().ServeHTTP(resp, httptest.NewRequest("GET", "/legacy/?a=1", nil))
+	if resp.Body.String() != "new a=1" {
+		t.Errorf("Unexpected response for rewritten request. Expected 'new a=1' got '%s'", resp.Body.String())
+	}
+
+	if err := os.WriteFile(rulesPath, []byte(`[{"pattern": "old", "target": "/new"}]`), 0644); err != nil {
+		panic(err)
+	}
+	if err := server.LoadRules(rulesPath); err == nil {
+		t.Errorf("No error seen for invalid rule")
+	}
+	if len(server.Rules()) != 2 {
+		t.Errorf("Rules changed after loading invalid rules")
+	}
+}
@@ -0,0 +1,596 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const tusVersion = "1.0.0"
+
+// TusOptions describes options for a resumable upload server
+type TusOptions struct {
+	// The store for uploads. Defaults to a disk store in Directory, see NewTusDiskStore.
+	Store TusStore
+	// The directory for uploads when Store is not set
+	Directory string
+	// The maximum length of any upload in bytes. Set to 0 for no limit.
+	MaxSize int64
+	// Incomplete uploads expire after this much time has passed since data was last received, and are removed. Set to 0
+	// to never expire uploads.
+	Expiration time.Duration
+	// Returns a string that identifies the user of a request from the result of AuthenticateMethod, such as a user ID.
+	// The owner is recorded when an upload is created, and requests for the upload from any other owner are answered as
+	// if the upload did not exist. If not set then the URL of an upload acts as a bearer token, anybody who knows it can
+	// append to, inspect, or terminate the upload.
+	Owner func(userData interface{}) string
+	// Called once all data for an upload has been received, with the request that completed the upload. The UserData of
+	// the request is the result of AuthenticateMethod from the handle options, which is the user that created the upload
+	// only if Owner is set. Read the data of the upload using Tus.Open. If an error is returned it is sent to the client,
+	// the upload is kept. Called only once for each upload, it is not called again for later requests to an upload that
+	// is already complete.
+	OnComplete func(request Request, upload TusUpload) *Error
+	// If true then completed uploads are removed from the store after OnComplete returns without an error
+	RemoveCompleted bool
+}
+
+// Tus describes a server for resumable uploads using the tus 1.0 protocol, supporting the creation, expiration, and
+// termination extensions. See https://tus.io/protocols/resumable-upload for details.
+type Tus struct {
+	prefix    string
+	options   TusOptions
+	store     TusStore
+	locks     map[string]*sync.Mutex
+	lock      *sync.Mutex
+	lastSweep time.Time
+}
+
+// Tus registers a resumable upload server under path. Clients create an upload by sending a POST request to path, and
+// then send the data with PATCH requests to the location returned. The pre-handle, rate limit, body length, and
+// authentication options from options are applied to all requests, so MaxBodyLength limits the size of each PATCH
+// request rather than the size of the upload.
+//
+// Will panic if neither a store nor a directory is configured, or if a handler is already mounted at path.
+//
+// For example:
+//
+//	server.Tus("/uploads/", web.TusOptions{
+//		Directory:  "/var/lib/uploads",
+//		Expiration: 24 * time.Hour,
+//		Owner: func(userData interface{}) string {
+//			return userData.(*User).ID
+//		},
+//		OnComplete: func(request web.Request, upload web.TusUpload) *web.Error {
+//			user := request.UserData.(*User)
+//			// Read the upload using tus.Open(upload.ID)
+//			return nil
+//		},
+//	}, web.HandleOptions{AuthenticateMethod: authenticate})
+func (s *Server) Tus(path string, tusOptions TusOptions, options HandleOptions) *Tus {
+	store := tusOptions.Store
+	if store == nil {
+		if tusOptions.Directory == "" {
+			panic("Tus server requires a store or directory")
+		}
+		store = NewTusDiskStore(tusOptions.Directory)
+	}
+
+	t := &Tus{
+		prefix:  strings.TrimRight(path, "/"),
+		options: tusOptions,
+		store:   store,
+		locks:   map[string]*sync.Mutex{},
+		lock:    &sync.Mutex{},
+	}
+	log.PDebug("Serving resumable uploads", map[string]interface{}{
+		"path": path,
+	})
+	s.router.Mount(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userData, ok := s.mountedPreHandle(w, r, options, "tus")
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+		defer func() {
+			if p := recover(); p != nil {
+				log.PError("Recovered from panic during tus handle", map[string]interface{}{
+					"error":  fmt.Sprintf("%v", p),
+					"route":  r.URL.Path,
+					"method": r.Method,
+					"stack":  string(debug.Stack()),
+				})
+				w.WriteHeader(500)
+			}
+		}()
+
+		t.serveHTTP(w, r, userData)
+		if !options.DontLogRequests {
+			log.PWrite(s.options().RequestLogLevel, "HTTP Request", map[string]interface{}{
+				"remote_addr": RealRemoteAddr(r),
+				"method":      r.Method,
+				"url":         r.URL,
+				"elapsed":     time.Since(start).String(),
+			})
+		}
+	}))
+	return t
+}
+
+// Open the data of the upload with id for reading. The caller must close the reader.
+func (t *Tus) Open(id string) (io.ReadCloser, error) {
+	return t.store.Open(id)
+}
+
+// Remove the upload with id and all of its data
+func (t *Tus) Remove(id string) error {
+	return t.store.Remove(id)
+}
+
+// RemoveExpired removes all incomplete uploads that have expired. Expired uploads are also removed periodically as
+// new uploads are created.
+func (t *Tus) RemoveExpired() error {
+	if t.options.Expiration <= 0 {
+		return nil
+	}
+
+	uploads, err := t.store.List()
+	if err != nil {
+		return err
+	}
+	for _, upload := range uploads {
+		if !t.isExpired(upload) {
+			continue
+		}
+		if err := t.removeExpired(upload.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeExpired removes the upload with id if it is still expired. Uploads that are receiving data are skipped.
+func (t *Tus) removeExpired(id string) error {
+	l, ok := t.uploadLock(id)
+	if !ok {
+		return nil
+	}
+	defer t.uploadUnlock(id, l)
+
+	// Data may have been received since the upload was listed
+	upload, err := t.store.Get(id)
+	if err == ErrTusUploadNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !t.isExpired(upload) {
+		return nil
+	}
+
+	log.PDebug("Removing expired upload", map[string]interface{}{
+		"id": id,
+	})
+	if err := t.store.Remove(id); err != nil && err != ErrTusUploadNotFound {
+		return err
+	}
+	return nil
+}
+
+func (t *Tus) isExpired(upload *TusUpload) bool {
+	return t.options.Expiration > 0 && !upload.IsComplete() && time.Now().After(t.expires(upload))
+}
+
+func (t *Tus) expires(upload *TusUpload) time.Time {
+	return upload.Updated.Add(t.options.Expiration)
+}
+
+func (t *Tus) extensions() string {
+	extensions := []string{"creation", "termination"}
+	if t.options.Expiration > 0 {
+		extensions = append(extensions, "expiration")
+	}
+	return strings.Join(extensions, ",")
+}
+
+// uploadLock returns the lock for the upload with id, which must be unlocked with uploadUnlock
+func (t *Tus) uploadLock(id string) (*sync.Mutex, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	l := t.locks[id]
+	if l == nil {
+		l = &sync.Mutex{}
+		t.locks[id] = l
+	}
+	return l, l.TryLock()
+}
+
+func (t *Tus) uploadUnlock(id string, l *sync.Mutex) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	l.Unlock()
+	delete(t.locks, id)
+}
+
+func (t *Tus) serveHTTP(w http.ResponseWriter, r *http.Request, userData interface{}) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && method == "POST" {
+		method = strings.ToUpper(override)
+	}
+
+	if method == "OPTIONS" {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", t.extensions())
+		if t.options.MaxSize > 0 {
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(t.options.MaxSize, 10))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	owner := ""
+	if t.options.Owner != nil {
+		owner = t.options.Owner(userData)
+	}
+
+	id := strings.Trim(r.URL.Path, "/")
+	if id == "" {
+		if method != "POST" {
+			w.Header().Set("Allow", "OPTIONS, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		t.create(w, r, owner, userData)
+		return
+	}
+
+	if !isTusID(id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch method {
+	case "HEAD":
+		t.head(w, id, owner)
+	case "PATCH":
+		t.patch(w, r, id, owner, userData)
+	case "DELETE":
+		t.terminate(w, id, owner)
+	default:
+		w.Header().Set("Allow", "OPTIONS, HEAD, PATCH, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *Tus) create(w http.ResponseWriter, r *http.Request, owner string, userData interface{}) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if t.options.MaxSize > 0 && length > t.options.MaxSize {
+		log.PWarn("Rejecting upload larger than maximum size", map[string]interface{}{
+			"length":   length,
+			"max_size": t.options.MaxSize,
+		})
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, ok := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t.sweep()
+
+	upload := TusUpload{
+		ID:       newTusID(),
+		Length:   length,
+		Metadata: metadata,
+		Owner:    owner,
+		Created:  time.Now(),
+	}
+	if err := t.store.Create(upload); err != nil {
+		log.PError("Error creating upload", map[string]interface{}{
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+	log.PDebug("Created upload", map[string]interface{}{
+		"id":     upload.ID,
+		"length": length,
+	})
+
+	if t.options.Expiration > 0 {
+		w.Header().Set("Upload-Expires", upload.Created.Add(t.options.Expiration).UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Location", t.prefix+"/"+upload.ID)
+
+	// An empty upload is complete as soon as it is created
+	if upload.IsComplete() {
+		if err := t.complete(r, upload, userData); err != nil {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(err.Code)
+			w.Write([]byte(err.Message))
+			return
+		}
+		if t.options.RemoveCompleted {
+			t.store.Remove(upload.ID)
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// complete is called once all data for an upload has been received by the request r
+func (t *Tus) complete(r *http.Request, upload TusUpload, userData interface{}) *Error {
+	log.PDebug("Completed upload", map[string]interface{}{
+		"id":     upload.ID,
+		"length": upload.Length,
+	})
+	if t.options.OnComplete == nil {
+		return nil
+	}
+
+	request := Request{
+		HTTP:       r,
+		Parameters: map[string]string{},
+		UserData:   userData,
+		cleanup:    newRequestCleanup(),
+	}
+	defer request.cleanup.run()
+	return t.options.OnComplete(request, upload)
+}
+
+// get returns the upload with id, writing an error response if it is not found, belongs to another owner, or has expired
+func (t *Tus) get(w http.ResponseWriter, id string, owner string) *TusUpload {
+	upload, err := t.store.Get(id)
+	if err == ErrTusUploadNotFound || (err == nil && upload.Owner != owner) {
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		log.PError("Error getting upload", map[string]interface{}{
+			"id":    id,
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return nil
+	}
+	if t.isExpired(upload) {
+		t.store.Remove(id)
+		w.WriteHeader(http.StatusGone)
+		return nil
+	}
+	return upload
+}
+
+func (t *Tus) setUploadHeaders(w http.ResponseWriter, upload *TusUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if t.options.Expiration > 0 && !upload.IsComplete() {
+		w.Header().Set("Upload-Expires", t.expires(upload).UTC().Format(http.TimeFormat))
+	}
+}
+
+func (t *Tus) head(w http.ResponseWriter, id string, owner string) {
+	w.Header().Set("Cache-Control", "no-store")
+	upload := t.get(w, id, owner)
+	if upload == nil {
+		return
+	}
+	t.setUploadHeaders(w, upload)
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if len(upload.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", formatTusMetadata(upload.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (t *Tus) patch(w http.ResponseWriter, r *http.Request, id string, owner string, userData interface{}) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	l, ok := t.uploadLock(id)
+	if !ok {
+		w.WriteHeader(http.StatusLocked)
+		return
+	}
+	defer t.uploadUnlock(id, l)
+
+	upload := t.get(w, id, owner)
+	if upload == nil {
+		return
+	}
+	if offset != upload.Offset {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if r.ContentLength > upload.Length-upload.Offset {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Only the request that receives the final bytes completes the upload
+	completed := false
+	if !upload.IsComplete() {
+		// The connection may be interrupted at any point, any data already received is kept
+		written, err := t.store.Append(id, offset, http.MaxBytesReader(w, r.Body, upload.Length-upload.Offset))
+		upload.Offset += written
+		upload.Updated = time.Now()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			case err == ErrTusOffsetMismatch:
+				w.WriteHeader(http.StatusConflict)
+			default:
+				log.PWarn("Error appending to upload", map[string]interface{}{
+					"id":      id,
+					"written": written,
+					"error":   err.Error(),
+				})
+				w.WriteHeader(500)
+			}
+			return
+		}
+		completed = upload.IsComplete()
+	}
+
+	if completed {
+		if err := t.complete(r, *upload, userData); err != nil {
+			t.setUploadHeaders(w, upload)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(err.Code)
+			w.Write([]byte(err.Message))
+			return
+		}
+	}
+
+	t.setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+
+	if completed && t.options.RemoveCompleted {
+		t.store.Remove(id)
+	}
+}
+
+func (t *Tus) terminate(w http.ResponseWriter, id string, owner string) {
+	l, ok := t.uploadLock(id)
+	if !ok {
+		w.WriteHeader(http.StatusLocked)
+		return
+	}
+	defer t.uploadUnlock(id, l)
+
+	upload, err := t.store.Get(id)
+	if err == nil && upload.Owner != owner {
+		err = ErrTusUploadNotFound
+	}
+	if err == nil {
+		err = t.store.Remove(id)
+	}
+	if err == ErrTusUploadNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.PError("Error removing upload", map[string]interface{}{
+			"id":    id,
+			"error": err.Error(),
+		})
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sweep removes expired uploads at most once per minute
+func (t *Tus) sweep() {
+	if t.options.Expiration <= 0 {
+		return
+	}
+	t.lock.Lock()
+	if time.Since(t.lastSweep) < time.Minute {
+		t.lock.Unlock()
+		return
+	}
+	t.lastSweep = time.Now()
+	t.lock.Unlock()
+
+	go func() {
+		if err := t.RemoveExpired(); err != nil {
+			log.PError("Error removing expired uploads", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+}
+
+func newTusID() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+func isTusID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// parseTusMetadata parses the Upload-Metadata header, a comma separated list of keys and base64 encoded values
+func parseTusMetadata(header string) (map[string]string, bool) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, true
+	}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, false
+		}
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, false
+			}
+			value = string(decoded)
+		}
+		metadata[parts[0]] = value
+	}
+	return metadata, true
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(metadata))
+	for _, key := range keys {
+		value := metadata[key]
+		if value == "" {
+			pairs = append(pairs, key)
+		} else {
+			pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrTusUploadNotFound is returned by a TusStore when there is no upload with the given ID
+var ErrTusUploadNotFound = errors.New("upload not found")
+
+// ErrTusOffsetMismatch is returned by a TusStore when appending to an upload at an offset that does not match the
+// current offset of the upload
+var ErrTusOffsetMismatch = errors.New("upload offset mismatch")
+
+// TusUpload describes a resumable upload
+type TusUpload struct {
+	// The unique ID of the upload
+	ID string `json:"id"`
+	// The total length of the upload in bytes
+	Length int64 `json:"length"`
+	// The number of bytes received. Populated by the store.
+	Offset int64 `json:"-"`
+	// Metadata provided by the client when the upload was created. Do not trust these values.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// The owner of the upload, see TusOptions.Owner. Empty if the server does not identify owners.
+	Owner string `json:"owner,omitempty"`
+	// When the upload was created
+	Created time.Time `json:"created"`
+	// When data was last appended to the upload, or when it was created. Populated by the store.
+	Updated time.Time `json:"-"`
+}
+
+// IsComplete returns true if all bytes of the upload have been received
+func (u TusUpload) IsComplete() bool {
+	return u.Offset >= u.Length
+}
+
+// TusStore describes a backend that stores resumable uploads. Stores must be safe for concurrent use, however the
+// server will never append to the same upload concurrently.
+type TusStore interface {
+	// Create a new empty upload
+	Create(upload TusUpload) error
+	// Get the upload with id, including its current offset. Returns ErrTusUploadNotFound if there is no upload.
+	Get(id string) (*TusUpload, error)
+	// Append data from reader to the upload with id, returning the number of bytes written. Any bytes written before an
+	// error must remain part of the upload. Returns ErrTusOffsetMismatch if offset is not the current offset.
+	Append(id string, offset int64, reader io.Reader) (int64, error)
+	// Open the data of the upload with id for reading
+	Open(id string) (io.ReadCloser, error)
+	// Remove the upload with id and all of its data
+	Remove(id string) error
+	// List all uploads
+	List() ([]*TusUpload, error)
+}
+
+// NewTusDiskStore returns a TusStore that stores uploads as files in directory. The data of each upload is written to a
+// file named with the upload ID, and a file with a ".info" extension holds the upload information.
+func NewTusDiskStore(directory string) TusStore {
+	return &tusDiskStore{Directory: directory}
+}
+
+type tusDiskStore struct {
+	Directory string
+}
+
+func (s *tusDiskStore) dataPath(id string) string {
+	return filepath.Join(s.Directory, id)
+}
+
+func (s *tusDiskStore) infoPath(id string) string {
+	return filepath.Join(s.Directory, id+".info")
+}
+
+func (s *tusDiskStore) Create(upload TusUpload) error {
+	if err := os.MkdirAll(s.Directory, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.dataPath(upload.ID), nil, 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.infoPath(upload.ID), data, 0644); err != nil {
+		os.Remove(s.dataPath(upload.ID))
+		return err
+	}
+	return nil
+}
+
+func (s *tusDiskStore) Get(id string) (*TusUpload, error) {
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrTusUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	upload := &TusUpload{}
+	if err := json.Unmarshal(data, upload); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(s.dataPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrTusUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	upload.Offset = info.Size()
+	upload.Updated = info.ModTime()
+	return upload, nil
+}
+
+func (s *tusDiskStore) Append(id string, offset int64, reader io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrTusUploadNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != offset {
+		return 0, ErrTusOffsetMismatch
+	}
+	return io.Copy(f, reader)
+}
+
+func (s *tusDiskStore) Open(id string) (io.ReadCloser, error) {
+	f, err := os.Open(s.dataPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrTusUploadNotFound
+	}
+	return f, err
+}
+
+func (s *tusDiskStore) Remove(id string) error {
+	infoErr := os.Remove(s.infoPath(id))
+	dataErr := os.Remove(s.dataPath(id))
+	if errors.Is(infoErr, fs.ErrNotExist) && errors.Is(dataErr, fs.ErrNotExist) {
+		return ErrTusUploadNotFound
+	}
+	if infoErr != nil && !errors.Is(infoErr, fs.ErrNotExist) {
+		return infoErr
+	}
+	if dataErr != nil && !errors.Is(dataErr, fs.ErrNotExist) {
+		return dataErr
+	}
+	return nil
+}
+
+func (s *tusDiskStore) List() ([]*TusUpload, error) {
+	entries, err := os.ReadDir(s.Directory)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	uploads := []*TusUpload{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".info") {
+			continue
+		}
+		upload, err := s.Get(strings.TrimSuffix(entry.Name(), ".info"))
+		if err != nil {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
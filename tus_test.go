@@ -0,0 +1,456 @@
+package web_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnepsnai/web"
+)
+
+func TestTusOptions(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.Tus("/"+path+"/", web.TusOptions{
+		Directory: t.TempDir(),
+		MaxSize:   64,
+	}, web.HandleOptions{})
+
+	req, err := http.NewRequest("OPTIONS", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 204 {
+		t.Errorf("Unexpected HTTP status code. Expected %d got %d", 204, resp.StatusCode)
+	}
+	if resp.Header.Get("Tus-Resumable") != "1.0.0" || resp.Header.Get("Tus-Version") != "1.0.0" || resp.Header.Get("Tus-Extension") != "creation,termination" || resp.Header.Get("Tus-Max-Size") != "64" {
+		t.Errorf("Unexpected OPTIONS headers: %v", resp.Header)
+	}
+}
+
+func TestTusCreate(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.Tus("/"+path+"/", web.TusOptions{
+		Directory: t.TempDir(),
+		MaxSize:   64,
+	}, web.HandleOptions{
+		AuthenticateMethod: func(request *http.Request) interface{} {
+			if request.Header.Get("X-User") == "" {
+				return nil
+			}
+			return request.Header.Get("X-User")
+		},
+	})
+
+	invalid := []struct {
+		Headers map[string]string
+		Status  int
+	}{
+		{map[string]string{"Tus-Resumable": "1.0.0", "Upload-Length": "10"}, 401},
+		{map[string]string{"X-User": "alice", "Tus-Resumable": "0.2.2", "Upload-Length": "10"}, 412},
+		{map[string]string{"X-User": "alice", "Tus-Resumable": "1.0.0"}, 400},
+		{map[string]string{"X-User": "alice", "Tus-Resumable": "1.0.0", "Upload-Length": "65"}, 413},
+		{map[string]string{"X-User": "alice", "Tus-Resumable": "1.0.0", "Upload-Length": "10", "Upload-Metadata": "filename !!!"}, 400},
+	}
+	for _, test := range invalid {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), nil)
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		for key, value := range test.Headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.Status {
+			t.Errorf("Unexpected HTTP status code for create with %v. Expected %d got %d", test.Headers, test.Status, resp.StatusCode)
+		}
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("X-User", "alice")
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Upload-Metadata", "filename aGVsbG8udHh0,public")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+	if resp.StatusCode != 201 || !strings.HasPrefix(location, "/"+path+"/") {
+		t.Fatalf("Unexpected response for create: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	req, err = http.NewRequest("HEAD", fmt.Sprintf("http://localhost:%d%s", server.ListenPort, location), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("X-User", "alice")
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected HTTP status code. Expected %d got %d", 200, resp.StatusCode)
+	}
+	if resp.Header.Get("Upload-Offset") != "0" || resp.Header.Get("Upload-Length") != "10" || resp.Header.Get("Upload-Metadata") != "filename aGVsbG8udHh0,public" || resp.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Unexpected HEAD headers: %v", resp.Header)
+	}
+}
+
+func TestTusPatch(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	var completed *web.TusUpload
+	var completedBy string
+	var completedData string
+
+	path := randomString(5)
+	var tus *web.Tus
+	tus = server.Tus("/"+path+"/", web.TusOptions{
+		Directory: t.TempDir(),
+		OnComplete: func(request web.Request, upload web.TusUpload) *web.Error {
+			completed = &upload
+			completedBy = request.UserData.(string)
+			reader, err := tus.Open(upload.ID)
+			if err != nil {
+				t.Errorf("Error opening completed upload: %s", err.Error())
+				return nil
+			}
+			defer reader.Close()
+			data, _ := io.ReadAll(reader)
+			completedData = string(data)
+			return nil
+		},
+	}, web.HandleOptions{
+		AuthenticateMethod: func(request *http.Request) interface{} {
+			return "alice"
+		},
+	})
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Upload-Metadata", "filename aGVsbG8udHh0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	url := fmt.Sprintf("http://localhost:%d%s", server.ListenPort, resp.Header.Get("Location"))
+
+	patches := []struct {
+		ContentType string
+		Offset      string
+		Body        string
+		Status      int
+	}{
+		{"", "0", "hello", 415},
+		{"application/offset+octet-stream", "0", "hello", 204},
+		{"application/offset+octet-stream", "0", "hello", 409},
+		{"application/offset+octet-stream", "5", "world and more", 413},
+		{"application/offset+octet-stream", "5", "world", 204},
+	}
+	for _, patch := range patches {
+		if completed != nil {
+			t.Fatalf("Upload completed early")
+		}
+		req, err := http.NewRequest("PATCH", url, strings.NewReader(patch.Body))
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Content-Type", patch.ContentType)
+		req.Header.Set("Upload-Offset", patch.Offset)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != patch.Status {
+			t.Errorf("Unexpected HTTP status code for PATCH '%s' at %s. Expected %d got %d", patch.Body, patch.Offset, patch.Status, resp.StatusCode)
+		}
+	}
+	if completed == nil || completed.Metadata["filename"] != "hello.txt" || completedBy != "alice" || completedData != "helloworld" {
+		t.Errorf("Unexpected completed upload: %+v by '%s' with '%s'", completed, completedBy, completedData)
+	}
+}
+
+func TestTusDelete(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.Tus("/"+path+"/", web.TusOptions{
+		Directory: t.TempDir(),
+	}, web.HandleOptions{})
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "10")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	url := fmt.Sprintf("http://localhost:%d%s", server.ListenPort, resp.Header.Get("Location"))
+
+	requests := []struct {
+		Method string
+		URL    string
+		Status int
+	}{
+		{"DELETE", url, 204},
+		{"HEAD", url, 404},
+		{"DELETE", url, 404},
+		{"HEAD", fmt.Sprintf("http://localhost:%d/%s/example", server.ListenPort, path), 404},
+		{"GET", url, 405},
+		{"GET", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), 405},
+	}
+	for _, request := range requests {
+		req, err := http.NewRequest(request.Method, request.URL, nil)
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != request.Status {
+			t.Errorf("Unexpected HTTP status code for %s %s. Expected %d got %d", request.Method, request.URL, request.Status, resp.StatusCode)
+		}
+	}
+}
+
+func TestTusExpiration(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	tus := server.Tus("/"+path, web.TusOptions{
+		Store:      web.NewTusDiskStore(t.TempDir()),
+		Expiration: 100 * time.Millisecond,
+	}, web.HandleOptions{})
+
+	locations := []string{}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Upload-Length", "10")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		expires, err := http.ParseTime(resp.Header.Get("Upload-Expires"))
+		if err != nil || resp.StatusCode != 201 || expires.Before(time.Now().Add(-time.Second)) {
+			t.Fatalf("Unexpected create response: %d %v", resp.StatusCode, resp.Header)
+		}
+		locations = append(locations, fmt.Sprintf("http://localhost:%d%s", server.ListenPort, resp.Header.Get("Location")))
+	}
+
+	req, err := http.NewRequest("OPTIONS", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if !strings.Contains(resp.Header.Get("Tus-Extension"), "expiration") {
+		t.Errorf("Expiration extension not advertised")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	req, err = http.NewRequest("HEAD", locations[0], nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 410 {
+		t.Errorf("Unexpected status for expired upload. Expected 410 got %d", resp.StatusCode)
+	}
+
+	if err := tus.RemoveExpired(); err != nil {
+		t.Fatalf("Error removing expired uploads: %s", err.Error())
+	}
+	req, err = http.NewRequest("HEAD", locations[1], nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Errorf("Unexpected status for removed upload. Expected 404 got %d", resp.StatusCode)
+	}
+}
+
+func TestTusCompleteOnce(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	completed := 0
+	path := randomString(5)
+	server.Tus("/"+path, web.TusOptions{
+		Directory: t.TempDir(),
+		OnComplete: func(request web.Request, upload web.TusUpload) *web.Error {
+			completed++
+			return nil
+		},
+	}, web.HandleOptions{})
+
+	// An empty upload is complete once created, the second request to each upload must not complete it again
+	for _, upload := range []string{"hello", ""} {
+		req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), nil)
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Upload-Length", fmt.Sprintf("%d", len(upload)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		url := fmt.Sprintf("http://localhost:%d%s", server.ListenPort, resp.Header.Get("Location"))
+
+		for _, offset := range []int{0, len(upload)} {
+			req, err := http.NewRequest("PATCH", url, strings.NewReader(upload[offset:]))
+			if err != nil {
+				t.Fatalf("Error forming HTTP request: %s", err.Error())
+			}
+			req.Header.Set("Tus-Resumable", "1.0.0")
+			req.Header.Set("Content-Type", "application/offset+octet-stream")
+			req.Header.Set("Upload-Offset", fmt.Sprintf("%d", offset))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Network error: %s", err.Error())
+			}
+			resp.Body.Close()
+			if resp.StatusCode != 204 {
+				t.Errorf("Unexpected status for PATCH at %d of '%s'. Expected 204 got %d", offset, upload, resp.StatusCode)
+			}
+		}
+	}
+	if completed != 2 {
+		t.Errorf("Unexpected number of completions. Expected 2 got %d", completed)
+	}
+}
+
+func TestTusOwner(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	var completedBy string
+	path := randomString(5)
+	server.Tus("/"+path+"/", web.TusOptions{
+		Directory: t.TempDir(),
+		Owner: func(userData interface{}) string {
+			return userData.(string)
+		},
+		OnComplete: func(request web.Request, upload web.TusUpload) *web.Error {
+			completedBy = upload.Owner
+			return nil
+		},
+	}, web.HandleOptions{
+		AuthenticateMethod: func(request *http.Request) interface{} {
+			if request.Header.Get("X-User") == "" {
+				return nil
+			}
+			return request.Header.Get("X-User")
+		},
+	})
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/%s/", server.ListenPort, path), nil)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	req.Header.Set("X-User", "alice")
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", "5")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	url := fmt.Sprintf("http://localhost:%d%s", server.ListenPort, resp.Header.Get("Location"))
+
+	// Requests from another user must not be able to tell that the upload exists
+	requests := []struct {
+		User   string
+		Method string
+		Status int
+	}{
+		{"mallory", "HEAD", 404},
+		{"mallory", "PATCH", 404},
+		{"mallory", "DELETE", 404},
+		{"alice", "HEAD", 200},
+		{"alice", "PATCH", 204},
+	}
+	for _, request := range requests {
+		req, err := http.NewRequest(request.Method, url, strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("Error forming HTTP request: %s", err.Error())
+		}
+		req.Header.Set("X-User", request.User)
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", "0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != request.Status {
+			t.Errorf("Unexpected HTTP status code for %s by %s. Expected %d got %d", request.Method, request.User, request.Status, resp.StatusCode)
+		}
+	}
+	if completedBy != "alice" {
+		t.Errorf("Unexpected owner of completed upload '%s'", completedBy)
+	}
+}
@@ -0,0 +1,393 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// UploadOptions describes limits for reading a multipart/form-data request body
+type UploadOptions struct {
+	// The maximum size in bytes of any single file. Set to 0 for no limit.
+	MaxFileSize int64
+	// The maximum combined size in bytes of all files and form values. Set to 0 for no limit.
+	MaxTotalSize int64
+	// The maximum size in bytes of any form value that is not a file. Defaults to 1 MiB.
+	MaxFieldSize int64
+	// The maximum number of files. Set to 0 for no limit.
+	MaxFiles int
+	// The content types of files that are accepted, determined by sniffing the file contents with
+	// http.DetectContentType rather than trusting the client. Entries ending with "/*" match any subtype, such as
+	// "image/*". If empty any content type is accepted.
+	AllowedMIMETypes []string
+	// Files larger than this many bytes are written to a temporary file rather than held in memory. Defaults to 1 MiB.
+	// Ignored for requests that are not served by a handle, such as mock requests, which hold all files in memory.
+	MemoryThreshold int64
+	// The directory for temporary files. Defaults to the system temporary directory.
+	TempDir string
+}
+
+func (o UploadOptions) maxFieldSize() int64 {
+	if o.MaxFieldSize <= 0 {
+		return 1024 * 1024
+	}
+	return o.MaxFieldSize
+}
+
+func (o UploadOptions) memoryThreshold() int64 {
+	if o.MemoryThreshold <= 0 {
+		return 1024 * 1024
+	}
+	return o.MemoryThreshold
+}
+
+// isAllowed returns true if the content type is permitted
+func (o UploadOptions) isAllowed(contentType string) bool {
+	if len(o.AllowedMIMETypes) == 0 {
+		return true
+	}
+	mimeType, _, _ := mime.ParseMediaType(contentType)
+	for _, allowed := range o.AllowedMIMETypes {
+		allowed = strings.ToLower(allowed)
+		if strings.HasSuffix(allowed, "/*") {
+			if strings.HasPrefix(mimeType, allowed[0:len(allowed)-1]) {
+				return true
+			}
+		} else if mimeType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+var errUploadTooLarge = errors.New("upload exceeds size limit")
+
+// uploadCounter tracks the total size of all parts in a multipart body
+type uploadCounter struct {
+	Total    int64
+	Limit    int64
+	Exceeded bool
+}
+
+// UploadPart describes a single part of a multipart/form-data request body. Reading from the part returns an error once
+// any size limit has been exceeded.
+type UploadPart struct {
+	// The name of the form field
+	FieldName string
+	// The name of the file provided by the client, without any directory. Empty if this part is not a file.
+	FileName string
+	// The content type of the file, determined by sniffing the file contents. Empty if this part is not a file.
+	ContentType string
+	// The content type of the file claimed by the client. Do not trust this value.
+	DeclaredContentType string
+
+	reader  io.Reader
+	read    int64
+	limit   int64
+	counter *uploadCounter
+}
+
+// IsFile returns true if this part is a file
+func (p *UploadPart) IsFile() bool {
+	return p.FileName != ""
+}
+
+func (p *UploadPart) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+	p.counter.Total += int64(n)
+	if isBodyTooLarge(err) {
+		p.counter.Exceeded = true
+		return n, err
+	}
+	if (p.limit > 0 && p.read > p.limit) || (p.counter.Limit > 0 && p.counter.Total > p.counter.Limit) {
+		p.counter.Exceeded = true
+		return n, errUploadTooLarge
+	}
+	return n, err
+}
+
+// partReadError returns the error for a part that could not be read
+func partReadError(err error) *Error {
+	if err == errUploadTooLarge || isBodyTooLarge(err) {
+		return CommonErrors.PayloadTooLarge
+	}
+	log.PError("Error reading multipart body", map[string]interface{}{
+		"error": err.Error(),
+	})
+	return ValidationError("Invalid multipart body")
+}
+
+// StreamMultipart will read a multipart/form-data request body one part at a time, calling handler for each part
+// without buffering it. Parts must be read by handler before it returns, any unread data is discarded. If handler
+// returns an error then no further parts are read and that error is returned.
+//
+// Returns CommonErrors.PayloadTooLarge if any size limit in options or the maximum body length of the handle is
+// exceeded, CommonErrors.UnsupportedMediaType if the request is not multipart/form-data or a file does not match
+// options.AllowedMIMETypes, or a validation error if there are too many files or the body is malformed.
+func (r Request) StreamMultipart(options UploadOptions, handler func(part *UploadPart) *Error) *Error {
+	mediaType, _, err := mime.ParseMediaType(r.HTTP.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return CommonErrors.UnsupportedMediaType
+	}
+	reader, err := r.HTTP.MultipartReader()
+	if err != nil {
+		return ValidationError("Invalid multipart body")
+	}
+
+	counter := &uploadCounter{Limit: options.MaxTotalSize}
+	files := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if counter.Exceeded || isBodyTooLarge(err) {
+				return CommonErrors.PayloadTooLarge
+			}
+			log.PError("Error reading multipart body", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return ValidationError("Invalid multipart body")
+		}
+
+		uploadPart := &UploadPart{
+			FieldName: part.FormName(),
+			FileName:  part.FileName(),
+			counter:   counter,
+		}
+		if uploadPart.IsFile() {
+			files++
+			if options.MaxFiles > 0 && files > options.MaxFiles {
+				part.Close()
+				return ValidationError("Too many files, at most %d are allowed", options.MaxFiles)
+			}
+
+			buffered := bufio.NewReaderSize(part, 512)
+			head, _ := buffered.Peek(512)
+			uploadPart.ContentType = http.DetectContentType(head)
+			uploadPart.DeclaredContentType = part.Header.Get("Content-Type")
+			uploadPart.reader = buffered
+			uploadPart.limit = options.MaxFileSize
+			if !options.isAllowed(uploadPart.ContentType) {
+				log.PWarn("Rejected upload with disallowed content type", map[string]interface{}{
+					"field":        uploadPart.FieldName,
+					"file_name":    uploadPart.FileName,
+					"content_type": uploadPart.ContentType,
+				})
+				part.Close()
+				return CommonErrors.UnsupportedMediaType
+			}
+		} else {
+			uploadPart.reader = part
+			uploadPart.limit = options.maxFieldSize()
+		}
+
+		handlerErr := handler(uploadPart)
+		if !counter.Exceeded && handlerErr == nil {
+			// Any unread data still counts toward the limits
+			_, err = io.Copy(io.Discard, uploadPart)
+		}
+		part.Close()
+		if counter.Exceeded {
+			log.PWarn("Rejected upload exceeding size limit", map[string]interface{}{
+				"field":     uploadPart.FieldName,
+				"file_name": uploadPart.FileName,
+			})
+			return CommonErrors.PayloadTooLarge
+		}
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if err != nil {
+			return ValidationError("Invalid multipart body")
+		}
+	}
+}
+
+// UploadedFile describes a file from a multipart/form-data request body read by ParseMultipart. Files are held in
+// memory or in a temporary file, which is removed automatically once the handle returns. Requests that are not served by
+// a handle, such as mock requests, have nothing to wait for, so their files are always held in memory.
+type UploadedFile struct {
+	// The name of the form field
+	FieldName string
+	// The name of the file provided by the client, without any directory
+	FileName string
+	// The content type of the file, determined by sniffing the file contents
+	ContentType string
+	// The content type of the file claimed by the client. Do not trust this value.
+	DeclaredContentType string
+	// The size of the file in bytes
+	Size int64
+
+	data     []byte
+	tempPath string
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// Open returns a reader for the contents of the file
+func (f *UploadedFile) Open() (io.ReadSeekCloser, error) {
+	if f.tempPath == "" {
+		return nopSeekCloser{bytes.NewReader(f.data)}, nil
+	}
+	return os.Open(f.tempPath)
+}
+
+// SaveAs will write the file to filePath, replacing any existing file. Because the temporary file is removed once
+// the handle returns, use this to keep the file.
+func (f *UploadedFile) SaveAs(filePath string) error {
+	if f.tempPath != "" && os.Rename(f.tempPath, filePath) == nil {
+		f.tempPath = filePath
+		f.data = nil
+		return nil
+	}
+
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// MultipartForm describes a multipart/form-data request body read by ParseMultipart
+type MultipartForm struct {
+	// Form values that are not files, keyed by field name
+	Values map[string][]string
+	// Files, keyed by field name
+	Files map[string][]*UploadedFile
+}
+
+// ParseMultipart will read an entire multipart/form-data request body. Files up to options.MemoryThreshold are held in
+// memory, larger files are written to temporary files. All temporary files are removed once the handle returns, use
+// UploadedFile.SaveAs to keep a file. See StreamMultipart for the errors returned.
+func (r Request) ParseMultipart(options UploadOptions) (*MultipartForm, *Error) {
+	form := &MultipartForm{
+		Values: map[string][]string{},
+		Files:  map[string][]*UploadedFile{},
+	}
+	tempFiles := []string{}
+
+	err := r.StreamMultipart(options, func(part *UploadPart) *Error {
+		if !part.IsFile() {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return partReadError(err)
+			}
+			form.Values[part.FieldName] = append(form.Values[part.FieldName], string(value))
+			return nil
+		}
+
+		file := &UploadedFile{
+			FieldName:           part.FieldName,
+			FileName:            part.FileName,
+			ContentType:         part.ContentType,
+			DeclaredContentType: part.DeclaredContentType,
+		}
+
+		// Without a handle to wait for there is nothing to remove temporary files, so the file is held in memory
+		threshold := options.memoryThreshold()
+		if r.cleanup == nil {
+			threshold = math.MaxInt64 - 1
+		}
+		buf := &bytes.Buffer{}
+		n, err := io.CopyN(buf, part, threshold+1)
+		if err != nil && err != io.EOF {
+			return partReadError(err)
+		}
+		file.Size = n
+		if n <= threshold {
+			file.data = buf.Bytes()
+		} else {
+			f, err := os.CreateTemp(options.TempDir, "upload-*")
+			if err != nil {
+				log.PError("Error creating temporary file for upload", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return CommonErrors.ServerError
+			}
+			tempFiles = append(tempFiles, f.Name())
+			r.cleanup.add(f.Name())
+			copied, err := io.Copy(f, io.MultiReader(buf, part))
+			f.Close()
+			if err != nil {
+				if err == errUploadTooLarge || isBodyTooLarge(err) {
+					return partReadError(err)
+				}
+				log.PError("Error writing temporary file for upload", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return CommonErrors.ServerError
+			}
+			file.Size = copied
+			file.tempPath = f.Name()
+		}
+
+		form.Files[part.FieldName] = append(form.Files[part.FieldName], file)
+		return nil
+	})
+	if err != nil {
+		for _, tempFile := range tempFiles {
+			os.Remove(tempFile)
+		}
+		return nil, err
+	}
+	return form, nil
+}
+
+// requestCleanup tracks temporary files created for a request that must be removed once the handle returns
+type requestCleanup struct {
+	lock  *sync.Mutex
+	paths []string
+}
+
+func newRequestCleanup() *requestCleanup {
+	return &requestCleanup{lock: &sync.Mutex{}}
+}
+
+// add will remove filePath when the handle returns. Does nothing if there is no handle to wait for, such as for a
+// mock request, so temporary files must not be created for those requests.
+func (c *requestCleanup) add(filePath string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paths = append(c.paths, filePath)
+}
+
+// run will remove all temporary files
+func (c *requestCleanup) run() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, filePath := range c.paths {
+		os.Remove(filePath)
+	}
+	c.paths = nil
+}
@@ -0,0 +1,329 @@
+package web_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ecnepsnai/web"
+)
+
+type testUpload struct {
+	Field    string
+	FileName string
+	Data     []byte
+}
+
+func multipartBody(t *testing.T, uploads []testUpload) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, upload := range uploads {
+		if upload.FileName == "" {
+			writer.WriteField(upload.Field, string(upload.Data))
+			continue
+		}
+		part, err := writer.CreateFormFile(upload.Field, upload.FileName)
+		if err != nil {
+			t.Fatalf("Error creating form file: %s", err.Error())
+		}
+		part.Write(upload.Data)
+	}
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+func TestRequestParseMultipart(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	dir := t.TempDir()
+	tempDir := t.TempDir()
+	options := web.UploadOptions{
+		MaxFileSize:     2048,
+		MemoryThreshold: 64,
+		TempDir:         tempDir,
+	}
+
+	var tempFilesDuringHandle int
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		form, err := request.ParseMultipart(options)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries, _ := os.ReadDir(tempDir)
+		tempFilesDuringHandle = len(entries)
+
+		result := map[string]string{"name": strings.Join(form.Values["name"], ",")}
+		for field, files := range form.Files {
+			file := files[0]
+			reader, rerr := file.Open()
+			if rerr != nil {
+				t.Errorf("Error opening uploaded file: %s", rerr.Error())
+				continue
+			}
+			data, _ := io.ReadAll(reader)
+			reader.Close()
+			if int64(len(data)) != file.Size {
+				t.Errorf("Unexpected size for uploaded file. Expected %d got %d", file.Size, len(data))
+			}
+			result[field] = file.FileName + ":" + file.ContentType
+			if field == "keep" {
+				if serr := file.SaveAs(filepath.Join(dir, "kept")); serr != nil {
+					t.Errorf("Error saving uploaded file: %s", serr.Error())
+				}
+			}
+		}
+		return result, nil, nil
+	}, web.HandleOptions{})
+
+	large := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 1024)...)
+	body, contentType := multipartBody(t, []testUpload{
+		{Field: "name", Data: []byte("example")},
+		{Field: "small", FileName: "small.txt", Data: []byte("hello")},
+		{Field: "keep", FileName: "../large.png", Data: large},
+	})
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), contentType, body)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected 200 got %d: %s", resp.StatusCode, data)
+	}
+	for _, expected := range []string{`"name":"example"`, `"small":"small.txt:text/plain; charset=utf-8"`, `"keep":"large.png:image/png"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Upload response missing '%s': %s", expected, data)
+		}
+	}
+	if tempFilesDuringHandle != 1 {
+		t.Errorf("Unexpected number of temporary files during handle. Expected 1 got %d", tempFilesDuringHandle)
+	}
+	if saved, _ := os.ReadFile(filepath.Join(dir, "kept")); !bytes.Equal(saved, large) {
+		t.Errorf("Saved file does not match upload")
+	}
+}
+
+func TestRequestParseMultipartCleanup(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	tempDir := t.TempDir()
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		_, err := request.ParseMultipart(web.UploadOptions{MemoryThreshold: 64, TempDir: tempDir})
+		return nil, nil, err
+	}, web.HandleOptions{})
+
+	large := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 1024)...)
+	body, contentType := multipartBody(t, []testUpload{
+		{Field: "a", FileName: "a.png", Data: large},
+		{Field: "b", FileName: "b.png", Data: large},
+	})
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), contentType, body)
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected HTTP status code. Expected 200 got %d", resp.StatusCode)
+	}
+
+	// Temporary files are removed once the handle returns
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("Temporary files not removed after handle: %d", len(entries))
+	}
+}
+
+func TestRequestParseMultipartLimits(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	tempDir := t.TempDir()
+	options := web.UploadOptions{
+		MaxFileSize:      2048,
+		MaxTotalSize:     4096,
+		MaxFiles:         2,
+		AllowedMIMETypes: []string{"image/*", "text/plain"},
+		MemoryThreshold:  64,
+		TempDir:          tempDir,
+	}
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		_, err := request.ParseMultipart(options)
+		return nil, nil, err
+	}, web.HandleOptions{})
+
+	check := func(uploads []testUpload, status int, description string) {
+		body, contentType := multipartBody(t, uploads)
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), contentType, body)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Unexpected status for %s. Expected %d got %d", description, status, resp.StatusCode)
+		}
+		if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+			t.Errorf("Temporary files not removed after %s: %d", description, len(entries))
+		}
+	}
+
+	large := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 1024)...)
+	check([]testUpload{{Field: "a", FileName: "a.png", Data: append(large, large...)}}, 413, "file over limit")
+	check([]testUpload{
+		{Field: "a", FileName: "a.png", Data: large},
+		{Field: "b", FileName: "b.png", Data: large},
+		{Field: "c", Data: bytes.Repeat([]byte("c"), 2048)},
+	}, 413, "total over limit")
+	check([]testUpload{
+		{Field: "a", FileName: "a.txt", Data: []byte("a")},
+		{Field: "b", FileName: "b.txt", Data: []byte("b")},
+		{Field: "c", FileName: "c.txt", Data: []byte("c")},
+	}, 400, "too many files")
+	check([]testUpload{{Field: "a", FileName: "a.png", Data: []byte("%PDF-1.4 not an image")}}, 415, "disallowed type")
+}
+
+func TestRequestParseMultipartNotMultipart(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		_, err := request.ParseMultipart(web.UploadOptions{})
+		return nil, nil, err
+	}, web.HandleOptions{})
+
+	resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Network error: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 415 {
+		t.Errorf("Unexpected HTTP status code. Expected 415 got %d", resp.StatusCode)
+	}
+}
+
+func TestRequestStreamMultipart(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.HTTP.POST("/"+path, func(w http.ResponseWriter, request web.Request) {
+		names := []string{}
+		err := request.StreamMultipart(web.UploadOptions{MaxFieldSize: 8}, func(part *web.UploadPart) *web.Error {
+			if part.FieldName == "stop" {
+				return web.ValidationError("Stopped")
+			}
+			if part.IsFile() {
+				// Only part of the file is read, the remainder is discarded
+				buf := make([]byte, 4)
+				io.ReadFull(part, buf)
+				names = append(names, part.FileName+"="+string(buf))
+				return nil
+			}
+			data, _ := io.ReadAll(part)
+			names = append(names, part.FieldName+"="+string(data))
+			return nil
+		})
+		if err != nil {
+			w.WriteHeader(err.Code)
+			w.Write([]byte(err.Message))
+			return
+		}
+		w.Write([]byte(strings.Join(names, ",")))
+	}, web.HandleOptions{})
+
+	do := func(uploads []testUpload) (int, string) {
+		body, contentType := multipartBody(t, uploads)
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), contentType, body)
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	status, body := do([]testUpload{
+		{Field: "first", Data: []byte("one")},
+		{Field: "file", FileName: "file.txt", Data: []byte(randomString(64))[0:4]},
+		{Field: "second", Data: []byte("two")},
+	})
+	if status != 200 || !strings.HasPrefix(body, "first=one,file.txt=") || !strings.HasSuffix(body, ",second=two") {
+		t.Errorf("Unexpected streamed response: %d %s", status, body)
+	}
+
+	if status, _ := do([]testUpload{{Field: "field", Data: []byte("too long for a field")}}); status != 413 {
+		t.Errorf("Unexpected status for field over limit. Expected 413 got %d", status)
+	}
+	if status, body := do([]testUpload{{Field: "stop", Data: []byte("x")}, {Field: "after", Data: []byte("x")}}); status != 400 || body != "Stopped" {
+		t.Errorf("Unexpected response for handler error: %d %s", status, body)
+	}
+}
+
+func TestRequestParseMultipartMock(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	body, contentType := multipartBody(t, []testUpload{{Field: "file", FileName: "file.txt", Data: bytes.Repeat([]byte("a"), 128)}})
+	httpRequest, err := http.NewRequest("POST", "/upload", body)
+	if err != nil {
+		t.Fatalf("Error forming HTTP request: %s", err.Error())
+	}
+	httpRequest.Header.Set("Content-Type", contentType)
+	request := web.MockRequest(web.MockRequestParameters{Request: httpRequest})
+
+	form, werr := request.ParseMultipart(web.UploadOptions{MemoryThreshold: 64, TempDir: tempDir})
+	if werr != nil {
+		t.Fatalf("Error parsing multipart body: %s", werr.Message)
+	}
+
+	// Without a handle to wait for, files are held in memory rather than in temporary files that would never be removed
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("Temporary file created for mock request: %d", len(entries))
+	}
+
+	reader, rerr := form.Files["file"][0].Open()
+	if rerr != nil {
+		t.Fatalf("Error opening uploaded file: %s", rerr.Error())
+	}
+	if data, _ := io.ReadAll(reader); len(data) != 128 {
+		t.Errorf("Unexpected size for uploaded file. Expected 128 got %d", len(data))
+	}
+	reader.Close()
+}
+
+func TestRequestParseMultipartTruncated(t *testing.T) {
+	t.Parallel()
+	server := newServer()
+
+	path := randomString(5)
+	server.API.POST("/"+path, func(request web.Request) (interface{}, *web.APIResponse, *web.Error) {
+		_, err := request.ParseMultipart(web.UploadOptions{})
+		return nil, nil, err
+	}, web.HandleOptions{})
+
+	for _, upload := range []testUpload{{Field: "name", Data: []byte("example")}, {Field: "file", FileName: "file.txt", Data: []byte("example")}} {
+		body, contentType := multipartBody(t, []testUpload{upload})
+		// Cut the body off partway through the value, before the closing boundary
+		truncated := body.Bytes()[0 : bytes.Index(body.Bytes(), []byte("example"))+3]
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%d/%s", server.ListenPort, path), contentType, bytes.NewReader(truncated))
+		if err != nil {
+			t.Fatalf("Network error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 400 {
+			t.Errorf("Unexpected HTTP status code for truncated %s. Expected 400 got %d", upload.Field, resp.StatusCode)
+		}
+	}
+}
@@ -25,7 +25,7 @@ func (s *Server) registerSocketEndpoint(method string, path string, handle Socke
 		"method": method,
 		"path":   path,
 	})
-	s.router.Handle(method, path, s.socketHandler(handle, options))
+	s.router.HandleWithOptions(method, path, s.socketHandler(handle, options), options.routeOptions(RouteKindSocket))
 }
 
 var upgrader = websocket.Upgrader{
@@ -70,7 +70,7 @@ func (s *Server) socketHandler(endpointHandle SocketHandle, options HandleOption
 					})
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusUnauthorized)
-					json.NewEncoder(w).Encode(Error{401, "Unauthorized"})
+					json.NewEncoder(w).Encode(Error{Code: 401, Message: "Unauthorized"})
 					return
 				}
 
@@ -88,13 +88,13 @@ func (s *Server) socketHandler(endpointHandle SocketHandle, options HandleOption
 			return
 		}
 		endpointHandle(Request{
-			Parameters: r.Parameters,
+			Parameters: routeParameters(r.Parameters),
 			UserData:   userData,
 		}, &WSConn{
 			conn,
 		})
 		if !options.DontLogRequests {
-			log.PWrite(s.Options.RequestLogLevel, "Websocket request", map[string]interface{}{
+			log.PWrite(s.options().RequestLogLevel, "Websocket request", map[string]interface{}{
 				"method":      r.HTTP.Method,
 				"url":         r.HTTP.RequestURI,
 				"remote_addr": RealRemoteAddr(r.HTTP),